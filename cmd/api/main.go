@@ -4,9 +4,12 @@ import (
 	"context"
 	"go-api-template/config"
 	"go-api-template/internal"
+	"go-api-template/internal/libs/logger"
+	"go-api-template/internal/libs/tracing"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
@@ -17,6 +20,7 @@ func main() {
 		logrus.WithError(err).Fatal("Failed to load config")
 		panic(err)
 	}
+	logger.Setup()
 
 	logrus.WithFields(logrus.Fields{
 		"env":     serverConfig.Env,
@@ -24,6 +28,19 @@ func main() {
 		"port":    serverConfig.Port,
 	}).Info("Loaded app config")
 
+	shutdownTracing, err := tracing.Setup(context.Background())
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to set up tracing")
+	}
+	defer func() {
+		// Flush the spans still buffered by the exporter.
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logrus.WithError(err).Warn("Failed to flush traces")
+		}
+	}()
+
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
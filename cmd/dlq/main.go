@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"go-api-template/config"
+	"go-api-template/internal/libs/logger"
+	"go-api-template/internal/libs/queue"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Inspect or replay the dead-lettered messages of a queue:
+//
+//	go run cmd/dlq/main.go -queue=users.created -action=inspect -limit=10
+//	go run cmd/dlq/main.go -queue=users.created -action=replay -limit=10
+func main() {
+	queueName := flag.String("queue", "", "queue whose dead letters are inspected or replayed")
+	action := flag.String("action", "inspect", "inspect or replay")
+	limit := flag.Int("limit", 10, "max number of messages")
+
+	_, err := config.LoadConfig()
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to load config")
+	}
+	logger.Setup()
+
+	if *queueName == "" {
+		logrus.Fatal("-queue is required")
+	}
+
+	rabbit := queue.NewRabbitMQ(config.CONFIG.RabbitMQURL, queue.RabbitMQOptions{ConfirmPublish: true})
+	if err := rabbit.Connect(); err != nil {
+		logrus.WithError(err).Fatal("Failed to connect to RabbitMQ")
+	}
+	defer rabbit.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	switch *action {
+	case "inspect":
+		deadLetters, err := rabbit.InspectDeadLetters(ctx, *queueName, *limit)
+		if err != nil {
+			logrus.WithError(err).Fatal("Failed to inspect dead letters")
+		}
+
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		for _, d := range deadLetters {
+			var body any = string(d.Body)
+			if json.Valid(d.Body) {
+				body = json.RawMessage(d.Body)
+			}
+			_ = encoder.Encode(map[string]any{
+				"routingKey":     d.RoutingKey,
+				"exchange":       d.Exchange,
+				"queue":          d.Queue,
+				"attempts":       d.Attempts,
+				"lastError":      d.LastError,
+				"deadLetteredAt": d.DeadLetteredAt,
+				"body":           body,
+			})
+		}
+		logrus.Infof("Inspected %d dead letters of %s", len(deadLetters), *queueName)
+
+	case "replay":
+		replayed, err := rabbit.ReplayDeadLetters(ctx, *queueName, *limit)
+		if err != nil {
+			logrus.WithError(err).Fatalf("Replay stopped after %d dead letters", replayed)
+		}
+		logrus.Infof("Replayed %d dead letters of %s", replayed, *queueName)
+
+	default:
+		logrus.Fatalf("Unknown action %q, use inspect or replay", *action)
+	}
+}
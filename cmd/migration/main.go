@@ -3,6 +3,7 @@ package main
 import (
 	"go-api-template/config"
 	"go-api-template/internal/libs/database"
+	"go-api-template/internal/libs/logger"
 	"os"
 
 	"github.com/sirupsen/logrus"
@@ -15,6 +16,7 @@ func main() {
 		logrus.WithError(err).Fatal("Failed to load config for migrations")
 		panic(err)
 	}
+	logger.Setup()
 
 	postgresDB, err := database.NewPostgresDB()
 	if err != nil {
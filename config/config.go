@@ -5,6 +5,7 @@ import (
 	"go-api-template/internal/libs/utils"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/sirupsen/logrus"
@@ -20,12 +21,44 @@ var CONFIG ConfigSchema = ConfigSchema{}
 
 const DefaultEnvFilename = ".env"
 
+// QueueBackend is the message broker implementation, see queue.Broker.
+type QueueBackend string
+
+const QueueBackendNone QueueBackend = "none"
+const QueueBackendRabbitMQ QueueBackend = "rabbitmq"
+const QueueBackendNATS QueueBackend = "nats"
+const QueueBackendMemory QueueBackend = "memory"
+
+// TracingExporter is where the OpenTelemetry spans are sent.
+type TracingExporter string
+
+const TracingExporterNone TracingExporter = "none"
+const TracingExporterOTLP TracingExporter = "otlp"
+const TracingExporterStdout TracingExporter = "stdout"
+
 // ConfigSchema should have the same structure as .env.
 type ConfigSchema struct {
 	Env     EnvType
 	Port    string
 	Version string
 
+	// LogLevel is a logrus level: debug, info, warn, error.
+	LogLevel string
+
+	// Health: each readiness check gets HealthCheckTimeout. On shutdown, readiness fails for ShutdownDrainDelay
+	// before the server stops accepting requests, so the load balancer has time to notice.
+	HealthCheckTimeout time.Duration
+	ShutdownDrainDelay time.Duration
+
+	// Metrics: /metrics is served on MetricsPort, or with the API when it is empty.
+	MetricsEnabled bool
+	MetricsPort    string
+
+	// Tracing
+	TracingExporter    TracingExporter
+	TracingServiceName string
+	TracingSampleRatio float64
+
 	AllowedOrigins []string
 
 	DevBaseURL  string
@@ -38,11 +71,38 @@ type ConfigSchema struct {
 	DbUser     string
 	DbPassword string
 
+	// Queue
+	QueueBackend QueueBackend
+
 	// RabbitMQ
 	RabbitMQEnabled  bool
 	RabbitMQURL      string
 	RabbitMQPrefetch int
 
+	RabbitMQPublishConfirm   bool
+	RabbitMQPublishMandatory bool
+	RabbitMQPublishTimeout   time.Duration
+
+	RabbitMQReconnectMinDelay time.Duration
+	RabbitMQReconnectMaxDelay time.Duration
+	RabbitMQShutdownTimeout   time.Duration
+
+	RabbitMQDirectReplyTo  bool
+	RabbitMQRequestTimeout time.Duration
+
+	// NATS JetStream
+	NatsURL      string
+	NatsPrefetch int
+	NatsAckWait  time.Duration
+
+	// Outbox
+	OutboxPollInterval time.Duration
+	OutboxBatchSize    int
+	OutboxRetention    time.Duration
+
+	// ProcessedMessagesRetention is how long consumers remember handled message IDs to skip duplicates.
+	ProcessedMessagesRetention time.Duration
+
 	// OpenAI
 	OpenaiApiKey string
 	// Anthropic
@@ -55,9 +115,15 @@ type ConfigSchema struct {
 	GroqApiKey string
 
 	// JWT
-	JwtSecret             string
-	JwtRefreshTokenSecret string
-	TokenExpiration       string
+	JwtSecret              string
+	JwtRefreshTokenSecret  string
+	TokenExpiration        string
+	RefreshTokenExpiration string
+	JwtIssuer              string
+	JwtAudience            string
+
+	// PaginationCursorSecret signs the list cursors, it defaults to JwtSecret.
+	PaginationCursorSecret string
 
 	// Mailgun
 	MailgunDomain string
@@ -92,9 +158,21 @@ func LoadConfig() (ConfigSchema, error) {
 	}
 
 	config := ConfigSchema{
-		Env:         EnvType(strings.ToUpper(os.Getenv("ENV"))),
-		Port:        os.Getenv("PORT"),
-		Version:     os.Getenv("VERSION"),
+		Env:      EnvType(strings.ToUpper(os.Getenv("ENV"))),
+		Port:     os.Getenv("PORT"),
+		Version:  os.Getenv("VERSION"),
+		LogLevel: strings.ToLower(os.Getenv("LOG_LEVEL")),
+
+		HealthCheckTimeout: utils.ParseDurationWithDefault(os.Getenv("HEALTH_CHECK_TIMEOUT"), 2*time.Second),
+		ShutdownDrainDelay: utils.ParseDurationWithDefault(os.Getenv("SHUTDOWN_DRAIN_DELAY"), 0),
+
+		MetricsEnabled: strings.ToLower(os.Getenv("METRICS_ENABLED")) == "true",
+		MetricsPort:    os.Getenv("METRICS_PORT"),
+
+		TracingExporter:    TracingExporter(strings.ToLower(os.Getenv("TRACING_EXPORTER"))),
+		TracingServiceName: os.Getenv("TRACING_SERVICE_NAME"),
+		TracingSampleRatio: utils.ParseFloatWithDefault(os.Getenv("TRACING_SAMPLE_RATIO"), 1),
+
 		DevBaseURL:  os.Getenv("DEV_BASE_URL"),
 		ProdBaseURL: os.Getenv("PROD_BASE_URL"),
 
@@ -110,6 +188,27 @@ func LoadConfig() (ConfigSchema, error) {
 		RabbitMQURL:      os.Getenv("RABBITMQ_URL"),
 		RabbitMQPrefetch: utils.ParseIntWithDefault(os.Getenv("RABBITMQ_PREFETCH"), 20),
 
+		RabbitMQPublishConfirm:   strings.ToLower(os.Getenv("RABBITMQ_PUBLISH_CONFIRM")) == "true",
+		RabbitMQPublishMandatory: strings.ToLower(os.Getenv("RABBITMQ_PUBLISH_MANDATORY")) == "true",
+		RabbitMQPublishTimeout:   utils.ParseDurationWithDefault(os.Getenv("RABBITMQ_PUBLISH_TIMEOUT"), 5*time.Second),
+
+		RabbitMQReconnectMinDelay: utils.ParseDurationWithDefault(os.Getenv("RABBITMQ_RECONNECT_MIN_DELAY"), 500*time.Millisecond),
+		RabbitMQReconnectMaxDelay: utils.ParseDurationWithDefault(os.Getenv("RABBITMQ_RECONNECT_MAX_DELAY"), 30*time.Second),
+		RabbitMQShutdownTimeout:   utils.ParseDurationWithDefault(os.Getenv("RABBITMQ_SHUTDOWN_TIMEOUT"), 30*time.Second),
+
+		RabbitMQDirectReplyTo:  strings.ToLower(os.Getenv("RABBITMQ_DIRECT_REPLY_TO")) == "true",
+		RabbitMQRequestTimeout: utils.ParseDurationWithDefault(os.Getenv("RABBITMQ_REQUEST_TIMEOUT"), 30*time.Second),
+
+		NatsURL:      os.Getenv("NATS_URL"),
+		NatsPrefetch: utils.ParseIntWithDefault(os.Getenv("NATS_PREFETCH"), 20),
+		NatsAckWait:  utils.ParseDurationWithDefault(os.Getenv("NATS_ACK_WAIT"), time.Minute),
+
+		OutboxPollInterval: utils.ParseDurationWithDefault(os.Getenv("OUTBOX_POLL_INTERVAL"), time.Second),
+		OutboxBatchSize:    utils.ParseIntWithDefault(os.Getenv("OUTBOX_BATCH_SIZE"), 100),
+		OutboxRetention:    utils.ParseDurationWithDefault(os.Getenv("OUTBOX_RETENTION"), 7*24*time.Hour),
+
+		ProcessedMessagesRetention: utils.ParseDurationWithDefault(os.Getenv("PROCESSED_MESSAGES_RETENTION"), 7*24*time.Hour),
+
 		OpenaiApiKey:     os.Getenv("OPENAI_API_KEY"),
 		AnthropicKey:     os.Getenv("ANTHROPIC_KEY"),
 		AnthropicVersion: os.Getenv("ANTHROPIC_VERSION"),
@@ -117,9 +216,14 @@ func LoadConfig() (ConfigSchema, error) {
 		GeminiApiKey:     os.Getenv("GEMINI_API_KEY"),
 		GroqApiKey:       os.Getenv("GROQ_API_KEY"),
 
-		JwtSecret:             os.Getenv("JWT_SECRET"),
-		JwtRefreshTokenSecret: os.Getenv("JWT_REFRESH_TOKEN_SECRET"),
-		TokenExpiration:       os.Getenv("TOKEN_EXPIRATION"),
+		JwtSecret:              os.Getenv("JWT_SECRET"),
+		JwtRefreshTokenSecret:  os.Getenv("JWT_REFRESH_TOKEN_SECRET"),
+		TokenExpiration:        os.Getenv("TOKEN_EXPIRATION"),
+		RefreshTokenExpiration: os.Getenv("REFRESH_TOKEN_EXPIRATION"),
+		JwtIssuer:              os.Getenv("JWT_ISSUER"),
+		JwtAudience:            os.Getenv("JWT_AUDIENCE"),
+
+		PaginationCursorSecret: os.Getenv("PAGINATION_CURSOR_SECRET"),
 
 		MailgunDomain: os.Getenv("MAILGUN_DOMAIN"),
 		MailgunApiKey: os.Getenv("MAILGUN_API_KEY"),
@@ -137,6 +241,27 @@ func LoadConfig() (ConfigSchema, error) {
 		GcpCredentialsToken: os.Getenv("GCP_CREDENTIALS_TOKEN"),
 	}
 
+	// RABBITMQ_ENABLED picks RabbitMQ when QUEUE_BACKEND is not set.
+	config.QueueBackend = QueueBackend(strings.ToLower(os.Getenv("QUEUE_BACKEND")))
+	if config.QueueBackend == "" {
+		config.QueueBackend = QueueBackendNone
+		if config.RabbitMQEnabled {
+			config.QueueBackend = QueueBackendRabbitMQ
+		}
+	}
+	config.RabbitMQEnabled = config.QueueBackend == QueueBackendRabbitMQ
+
+	if config.PaginationCursorSecret == "" {
+		config.PaginationCursorSecret = config.JwtSecret
+	}
+
+	if config.TracingExporter == "" {
+		config.TracingExporter = TracingExporterNone
+	}
+	if config.TracingServiceName == "" {
+		config.TracingServiceName = "go-api-template"
+	}
+
 	// All config variables in the app can be accessed by config.CONFIG anywhere in the app.
 	CONFIG = config
 
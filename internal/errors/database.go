@@ -0,0 +1,80 @@
+package errs
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Postgres error codes, see https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	pgUniqueViolation           = "23505"
+	pgForeignKeyViolation       = "23503"
+	pgCheckViolation            = "23514"
+	pgInvalidTextRepresentation = "22P02"
+)
+
+// FromDatabaseError translates errors returned by sqlx/pq into typed HTTP errors so they
+// don't end up as a 500. entity is used in the message, ex: "user".
+// The original error stays reachable with errors.Is/errors.As.
+func FromDatabaseError(err error, entity string) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return &HTTPError{ErrorCode: NotFound, StatusCode: http.StatusNotFound, Message: fmt.Sprintf("%s not found", capitalize(entity)), cause: err}
+	}
+
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+
+	switch string(pqErr.Code) {
+	case pgUniqueViolation:
+		return &HTTPError{ErrorCode: AlreadyExists, StatusCode: http.StatusConflict, Message: fmt.Sprintf("%s already exists", capitalize(entity)), cause: err}
+	case pgForeignKeyViolation:
+		return &HTTPError{ErrorCode: InvalidReference, StatusCode: http.StatusUnprocessableEntity, Message: fmt.Sprintf("%s references a resource that doesn't exist", capitalize(entity)), cause: err}
+	case pgCheckViolation:
+		return &HTTPError{ErrorCode: ValidationFailed, StatusCode: http.StatusUnprocessableEntity, Message: "Validation failed", Fields: checkViolationFields(pqErr), cause: err}
+	case pgInvalidTextRepresentation:
+		// The Postgres message names types and values, it stays in the cause.
+		return &HTTPError{ErrorCode: InvalidInput, StatusCode: http.StatusBadRequest, Message: fmt.Sprintf("Invalid %s input", entity), cause: err}
+	default:
+		return err
+	}
+}
+
+// IsUniqueViolation reports if err was caused by a violation of the given unique constraint.
+func IsUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return string(pqErr.Code) == pgUniqueViolation && pqErr.Constraint == constraint
+}
+
+// checkViolationFields reports the failing column when Postgres knows it. Domain checks (ex: email)
+// don't carry a column, the constraint name is the best hint then.
+func checkViolationFields(pqErr *pq.Error) []FieldError {
+	field := pqErr.Column
+	if field == "" {
+		field = pqErr.Constraint
+	}
+	if field == "" {
+		return nil
+	}
+	return []FieldError{{Field: field, Rule: "check", Message: "is invalid"}}
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return "Resource"
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
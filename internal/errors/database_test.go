@@ -0,0 +1,126 @@
+package errs
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestFromDatabaseError(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantStatus  int
+		wantCode    ErrorCode
+		wantMessage string
+	}{
+		{
+			name:        "no rows",
+			err:         fmt.Errorf("get user: %w", sql.ErrNoRows),
+			wantStatus:  http.StatusNotFound,
+			wantCode:    NotFound,
+			wantMessage: "User not found",
+		},
+		{
+			name:        "unique violation",
+			err:         &pq.Error{Code: pgUniqueViolation, Message: `duplicate key value violates unique constraint "email_unique"`},
+			wantStatus:  http.StatusConflict,
+			wantCode:    AlreadyExists,
+			wantMessage: "User already exists",
+		},
+		{
+			name:        "foreign key violation",
+			err:         &pq.Error{Code: pgForeignKeyViolation, Message: `insert or update on table "refresh_tokens" violates foreign key constraint`},
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantCode:    InvalidReference,
+			wantMessage: "User references a resource that doesn't exist",
+		},
+		{
+			name:        "invalid text representation",
+			err:         &pq.Error{Code: pgInvalidTextRepresentation, Message: `invalid input syntax for type uuid: "not-a-uuid"`},
+			wantStatus:  http.StatusBadRequest,
+			wantCode:    InvalidInput,
+			wantMessage: "Invalid user input",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := FromDatabaseError(tt.err, "user")
+			var httpErr *HTTPError
+			if !errors.As(err, &httpErr) {
+				t.Fatalf("FromDatabaseError = %v, want an *HTTPError", err)
+			}
+			if httpErr.StatusCode != tt.wantStatus || httpErr.ErrorCode != tt.wantCode || httpErr.Message != tt.wantMessage {
+				t.Errorf("got %d/%d %q, want %d/%d %q", httpErr.StatusCode, httpErr.ErrorCode, httpErr.Message, tt.wantStatus, tt.wantCode, tt.wantMessage)
+			}
+			if !errors.Is(err, tt.err) {
+				t.Error("the database error is not the cause")
+			}
+
+			var pqErr *pq.Error
+			if errors.As(tt.err, &pqErr) && strings.Contains(httpErr.Message, pqErr.Message) {
+				t.Errorf("message %q leaks the Postgres message", httpErr.Message)
+			}
+		})
+	}
+}
+
+func TestFromDatabaseErrorKeepsOtherErrors(t *testing.T) {
+	for _, err := range []error{
+		&pq.Error{Code: "40001", Message: "could not serialize access"},
+		errors.New("connection refused"),
+	} {
+		if got := FromDatabaseError(err, "user"); got != err {
+			t.Errorf("FromDatabaseError(%v) = %v, want it unchanged", err, got)
+		}
+	}
+	if FromDatabaseError(nil, "user") != nil {
+		t.Error("FromDatabaseError(nil) is not nil")
+	}
+}
+
+func TestFromDatabaseErrorCheckViolation(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        *pq.Error
+		wantFields []FieldError
+	}{
+		{
+			name:       "column",
+			err:        &pq.Error{Code: pgCheckViolation, Column: "first_name", Constraint: "users_first_name_check"},
+			wantFields: []FieldError{{Field: "first_name", Rule: "check", Message: "is invalid"}},
+		},
+		{
+			// Domain checks, ex: email, only carry the constraint.
+			name:       "constraint only",
+			err:        &pq.Error{Code: pgCheckViolation, Constraint: "email_check"},
+			wantFields: []FieldError{{Field: "email_check", Rule: "check", Message: "is invalid"}},
+		},
+		{
+			name: "neither",
+			err:  &pq.Error{Code: pgCheckViolation},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var httpErr *HTTPError
+			if !errors.As(FromDatabaseError(tt.err, "user"), &httpErr) {
+				t.Fatal("FromDatabaseError didn't return an *HTTPError")
+			}
+			if httpErr.StatusCode != http.StatusUnprocessableEntity || httpErr.ErrorCode != ValidationFailed {
+				t.Errorf("got %d/%d, want a 422 validation failed", httpErr.StatusCode, httpErr.ErrorCode)
+			}
+			if !slices.Equal(httpErr.Fields, tt.wantFields) {
+				t.Errorf("fields = %+v, want %+v", httpErr.Fields, tt.wantFields)
+			}
+		})
+	}
+}
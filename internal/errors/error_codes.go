@@ -3,6 +3,19 @@ package errs
 type ErrorCode int
 
 const (
-	Unknown            ErrorCode = 0
-	EmailAlreadyExists ErrorCode = 1
+	Unknown                 ErrorCode = 0
+	EmailAlreadyExists      ErrorCode = 1
+	Unauthorized            ErrorCode = 2
+	InvalidCredentials      ErrorCode = 3
+	InvalidRefreshToken     ErrorCode = 4
+	RefreshTokenReused      ErrorCode = 5
+	InvalidPaginationParams ErrorCode = 6
+	ValidationFailed        ErrorCode = 7
+	InvalidRequestBody      ErrorCode = 8
+	RequestBodyTooLarge     ErrorCode = 9
+	NotFound                ErrorCode = 10
+	AlreadyExists           ErrorCode = 11
+	InvalidReference        ErrorCode = 12
+	InvalidInput            ErrorCode = 13
+	Forbidden               ErrorCode = 14
 )
@@ -1,15 +1,30 @@
 package errs
 
 type HTTPError struct {
-	ErrorCode  ErrorCode `json:"errorCode,omitempty"`
-	StatusCode int       `json:"statusCode"`
-	Message    string    `json:"message"`
+	ErrorCode  ErrorCode    `json:"errorCode,omitempty"`
+	StatusCode int          `json:"statusCode"`
+	Message    string       `json:"message"`
+	Fields     []FieldError `json:"fields,omitempty"`
+
+	// cause is the underlying error, ex: the database error the HTTPError was translated from.
+	cause error
+}
+
+// FieldError describes why a single field of a request failed validation.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
 }
 
 func (e *HTTPError) Error() string {
 	return e.Message
 }
 
+func (e *HTTPError) Unwrap() error {
+	return e.cause
+}
+
 func New(errorCode ErrorCode, statusCode int, msg string) error {
 	return &HTTPError{
 		ErrorCode:  errorCode,
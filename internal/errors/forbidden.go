@@ -0,0 +1,7 @@
+package errs
+
+import "net/http"
+
+func NewForbiddenError(msg string) *HTTPError {
+	return &HTTPError{ErrorCode: Forbidden, StatusCode: http.StatusForbidden, Message: msg}
+}
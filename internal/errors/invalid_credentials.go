@@ -0,0 +1,7 @@
+package errs
+
+import "net/http"
+
+func NewInvalidCredentialsError() *HTTPError {
+	return &HTTPError{ErrorCode: InvalidCredentials, StatusCode: http.StatusUnauthorized, Message: "Invalid email or password"}
+}
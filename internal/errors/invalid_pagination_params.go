@@ -0,0 +1,7 @@
+package errs
+
+import "net/http"
+
+func NewInvalidPaginationParamsError(msg string) *HTTPError {
+	return &HTTPError{ErrorCode: InvalidPaginationParams, StatusCode: http.StatusBadRequest, Message: msg}
+}
@@ -0,0 +1,11 @@
+package errs
+
+import "net/http"
+
+func NewInvalidRefreshTokenError() *HTTPError {
+	return &HTTPError{ErrorCode: InvalidRefreshToken, StatusCode: http.StatusUnauthorized, Message: "Invalid refresh token"}
+}
+
+func NewRefreshTokenReusedError() *HTTPError {
+	return &HTTPError{ErrorCode: RefreshTokenReused, StatusCode: http.StatusUnauthorized, Message: "Refresh token was already used, all sessions of this login were revoked"}
+}
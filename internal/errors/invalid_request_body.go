@@ -0,0 +1,14 @@
+package errs
+
+import (
+	"fmt"
+	"net/http"
+)
+
+func NewInvalidRequestBodyError(msg string) *HTTPError {
+	return &HTTPError{ErrorCode: InvalidRequestBody, StatusCode: http.StatusBadRequest, Message: msg}
+}
+
+func NewRequestBodyTooLargeError(limit int64) *HTTPError {
+	return &HTTPError{ErrorCode: RequestBodyTooLarge, StatusCode: http.StatusRequestEntityTooLarge, Message: fmt.Sprintf("Request body must not be larger than %d bytes", limit)}
+}
@@ -0,0 +1,7 @@
+package errs
+
+import "net/http"
+
+func NewUnauthorizedError(msg string) *HTTPError {
+	return &HTTPError{ErrorCode: Unauthorized, StatusCode: http.StatusUnauthorized, Message: msg}
+}
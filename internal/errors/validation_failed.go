@@ -0,0 +1,7 @@
+package errs
+
+import "net/http"
+
+func NewValidationFailedError(fields []FieldError) *HTTPError {
+	return &HTTPError{ErrorCode: ValidationFailed, StatusCode: http.StatusUnprocessableEntity, Message: "Validation failed", Fields: fields}
+}
@@ -0,0 +1,52 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"go-api-template/config"
+	"go-api-template/internal/libs/health"
+	"net/http"
+)
+
+// connectionState is implemented by the brokers that hold a connection, see RabbitMQ.IsConnected.
+type connectionState interface {
+	IsConnected() bool
+}
+
+// newHealth registers the readiness checks of the dependencies: Postgres, and the broker when it holds
+// a connection (RabbitMQ: the connection and its channels, NATS: the connection).
+func (s *Server) newHealth() *health.Health {
+	h := health.NewHealth(config.CONFIG.HealthCheckTimeout)
+
+	h.Register("postgres", func(ctx context.Context) error {
+		return s.PostgresDB.Database.PingContext(ctx)
+	})
+
+	if broker, ok := s.Broker.(connectionState); ok {
+		h.Register(string(config.CONFIG.QueueBackend), func(ctx context.Context) error {
+			if !broker.IsConnected() {
+				return errors.New("connection or channel is closed")
+			}
+			return nil
+		})
+	}
+	return h
+}
+
+// HealthzHandler answers as long as the process serves requests, for liveness probes. It doesn't check
+// the dependencies: restarting the app doesn't fix a database outage.
+func (s *Server) HealthzHandler(w http.ResponseWriter, r *http.Request) {
+	s.ResponseRenderer.JSON(w, http.StatusOK, map[string]string{"status": health.StatusOK})
+}
+
+// ReadyzHandler reports the status of every dependency, with a 503 when one is down or the server is
+// draining, for readiness probes and load balancers.
+func (s *Server) ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	report := s.Health.Ready(r.Context())
+
+	statusCode := http.StatusOK
+	if report.Status != health.StatusOK {
+		statusCode = http.StatusServiceUnavailable
+	}
+	s.ResponseRenderer.JSONData(w, statusCode, report)
+}
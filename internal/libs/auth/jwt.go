@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go-api-template/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type TokenType string
+
+const AccessTokenType TokenType = "access"
+const RefreshTokenType TokenType = "refresh"
+
+const defaultAccessTokenExpiration = 15 * time.Minute
+const defaultRefreshTokenExpiration = 30 * 24 * time.Hour
+const defaultIssuer = "go-api-template"
+
+var ErrInvalidTokenType = errors.New("invalid token type")
+
+// Claims are the typed claims carried by the tokens issued by the API.
+type Claims struct {
+	Email     string    `json:"email,omitempty"`
+	TokenType TokenType `json:"typ"`
+	jwt.RegisteredClaims
+}
+
+// UserID returns the subject of the token, which is always the user id.
+func (c *Claims) UserID() string {
+	return c.Subject
+}
+
+// AccessTokenExpiration parses config.CONFIG.TokenExpiration (ex: 15m, 1h) and falls back to 15 minutes.
+func AccessTokenExpiration() time.Duration {
+	d, err := time.ParseDuration(config.CONFIG.TokenExpiration)
+	if err != nil || d <= 0 {
+		return defaultAccessTokenExpiration
+	}
+	return d
+}
+
+// RefreshTokenExpiration parses config.CONFIG.RefreshTokenExpiration and falls back to 30 days.
+func RefreshTokenExpiration() time.Duration {
+	d, err := time.ParseDuration(config.CONFIG.RefreshTokenExpiration)
+	if err != nil || d <= 0 {
+		return defaultRefreshTokenExpiration
+	}
+	return d
+}
+
+func issuer() string {
+	if config.CONFIG.JwtIssuer == "" {
+		return defaultIssuer
+	}
+	return config.CONFIG.JwtIssuer
+}
+
+func audience() string {
+	if config.CONFIG.JwtAudience == "" {
+		return defaultIssuer
+	}
+	return config.CONFIG.JwtAudience
+}
+
+// GenerateAccessToken signs a short lived access token for the user with JwtSecret.
+func GenerateAccessToken(userID string, email string) (string, time.Time, error) {
+	expiresAt := time.Now().Add(AccessTokenExpiration())
+	claims := Claims{
+		Email:     email,
+		TokenType: AccessTokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   userID,
+			Issuer:    issuer(),
+			Audience:  jwt.ClaimStrings{audience()},
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	token, err := signToken(claims, config.CONFIG.JwtSecret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// ParseAccessToken verifies the signature, expiry, issuer and audience of an access token.
+func ParseAccessToken(token string) (*Claims, error) {
+	return parseToken(token, config.CONFIG.JwtSecret, AccessTokenType)
+}
+
+// GenerateRefreshToken signs a refresh token with JwtRefreshTokenSecret.
+// tokenID is stored as the jti claim so the token can be matched with its server-side record.
+func GenerateRefreshToken(userID string, tokenID string) (string, time.Time, error) {
+	expiresAt := time.Now().Add(RefreshTokenExpiration())
+	claims := Claims{
+		TokenType: RefreshTokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
+			Subject:   userID,
+			Issuer:    issuer(),
+			Audience:  jwt.ClaimStrings{audience()},
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	token, err := signToken(claims, config.CONFIG.JwtRefreshTokenSecret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// ParseRefreshToken verifies a refresh token the same way as ParseAccessToken and requires a jti claim.
+func ParseRefreshToken(token string) (*Claims, error) {
+	claims, err := parseToken(token, config.CONFIG.JwtRefreshTokenSecret, RefreshTokenType)
+	if err != nil {
+		return nil, err
+	}
+	if claims.ID == "" {
+		return nil, fmt.Errorf("%w: missing jti", jwt.ErrTokenInvalidClaims)
+	}
+	return claims, nil
+}
+
+func signToken(claims Claims, secret string) (string, error) {
+	if secret == "" {
+		return "", errors.New("jwt secret is empty")
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	if err != nil {
+		return "", fmt.Errorf("sign token err: %w", err)
+	}
+	return signed, nil
+}
+
+func parseToken(token string, secret string, tokenType TokenType) (*Claims, error) {
+	if secret == "" {
+		return nil, errors.New("jwt secret is empty")
+	}
+
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		return []byte(secret), nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(issuer()),
+		jwt.WithAudience(audience()),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.TokenType != tokenType {
+		return nil, ErrInvalidTokenType
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", jwt.ErrTokenInvalidClaims)
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,37 @@
+package correlation
+
+import "context"
+
+// RequestIDHeader is the HTTP header carrying the request ID, it is accepted from clients and echoed back.
+const RequestIDHeader = "X-Request-ID"
+
+type correlationIDContextKey struct{}
+type causationIDContextKey struct{}
+
+// WithCorrelationID stores the ID shared by everything done for one originating request:
+// the HTTP request ID, then the correlation ID of the messages it caused.
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, correlationIDContextKey{}, id)
+}
+
+func CorrelationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDContextKey{}).(string)
+	return id
+}
+
+// WithCausationID stores the ID of the message being handled, messages published while
+// handling it record it as their causation ID.
+func WithCausationID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, causationIDContextKey{}, id)
+}
+
+func CausationID(ctx context.Context) string {
+	id, _ := ctx.Value(causationIDContextKey{}).(string)
+	return id
+}
@@ -3,6 +3,8 @@ package database
 import (
 	"errors"
 	"fmt"
+	"strconv"
+	"time"
 
 	"go-api-template/config"
 
@@ -65,3 +67,9 @@ func (s *PostgresDB) RunMigrations(migrationsPath string) error {
 func (s *PostgresDB) Close() error {
 	return s.Database.Close()
 }
+
+// Interval formats d as a Postgres interval, to compute deadlines in SQL: `CURRENT_TIMESTAMP + $1::interval`.
+// The database clock is then the only one, app instances with skewed clocks agree on the deadlines.
+func Interval(d time.Duration) string {
+	return strconv.FormatInt(d.Microseconds(), 10) + " microseconds"
+}
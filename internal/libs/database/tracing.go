@@ -0,0 +1,108 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "go-api-template/database"
+
+// tracedQuerier starts a span for every query run through the Querier.
+type tracedQuerier struct {
+	Querier
+}
+
+// TraceQuerier wraps q so every query gets a client span named by its SQL operation, with the query
+// text as attribute. Arguments are never recorded. Queries outside a trace, ex: the outbox relay
+// polling, are not traced, they would each start a trace of their own.
+func TraceQuerier(q Querier) Querier {
+	if _, ok := q.(tracedQuerier); ok || q == nil {
+		return q
+	}
+	return tracedQuerier{Querier: q}
+}
+
+func (q tracedQuerier) GetContext(ctx context.Context, dest any, query string, args ...any) (err error) {
+	ctx, span := startQuerySpan(ctx, query)
+	defer func() { endQuerySpan(span, err) }()
+	return q.Querier.GetContext(ctx, dest, query, args...)
+}
+
+func (q tracedQuerier) SelectContext(ctx context.Context, dest any, query string, args ...any) (err error) {
+	ctx, span := startQuerySpan(ctx, query)
+	defer func() { endQuerySpan(span, err) }()
+	return q.Querier.SelectContext(ctx, dest, query, args...)
+}
+
+func (q tracedQuerier) NamedExecContext(ctx context.Context, query string, arg any) (_ sql.Result, err error) {
+	ctx, span := startQuerySpan(ctx, query)
+	defer func() { endQuerySpan(span, err) }()
+	return q.Querier.NamedExecContext(ctx, query, arg)
+}
+
+func (q tracedQuerier) ExecContext(ctx context.Context, query string, args ...any) (_ sql.Result, err error) {
+	ctx, span := startQuerySpan(ctx, query)
+	defer func() { endQuerySpan(span, err) }()
+	return q.Querier.ExecContext(ctx, query, args...)
+}
+
+func (q tracedQuerier) QueryContext(ctx context.Context, query string, args ...any) (_ *sql.Rows, err error) {
+	ctx, span := startQuerySpan(ctx, query)
+	defer func() { endQuerySpan(span, err) }()
+	return q.Querier.QueryContext(ctx, query, args...)
+}
+
+func (q tracedQuerier) QueryxContext(ctx context.Context, query string, args ...any) (_ *sqlx.Rows, err error) {
+	ctx, span := startQuerySpan(ctx, query)
+	defer func() { endQuerySpan(span, err) }()
+	return q.Querier.QueryxContext(ctx, query, args...)
+}
+
+// QueryRowxContext ends the span when the query was sent, the row is scanned later by the caller.
+func (q tracedQuerier) QueryRowxContext(ctx context.Context, query string, args ...any) *sqlx.Row {
+	ctx, span := startQuerySpan(ctx, query)
+	row := q.Querier.QueryRowxContext(ctx, query, args...)
+	endQuerySpan(span, row.Err())
+	return row
+}
+
+func startQuerySpan(ctx context.Context, query string) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, trace.SpanFromContext(context.Background())
+	}
+
+	operation := queryOperation(query)
+	return otel.Tracer(tracerName).Start(ctx, operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNamePostgreSQL,
+			semconv.DBOperationNameKey.String(operation),
+			semconv.DBQueryTextKey.String(strings.TrimSpace(query)),
+		),
+	)
+}
+
+// endQuerySpan ends span, sql.ErrNoRows is an expected result and not recorded as an error.
+func endQuerySpan(span trace.Span, err error) {
+	if err != nil && err != sql.ErrNoRows {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// queryOperation returns the first keyword of the query, ex: SELECT.
+func queryOperation(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "QUERY"
+	}
+	return strings.ToUpper(fields[0])
+}
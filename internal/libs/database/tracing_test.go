@@ -0,0 +1,108 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"go-api-template/internal/libs/tracing"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// stubQuerier answers the calls the tests make, any other call panics on the nil Querier.
+type stubQuerier struct {
+	Querier
+	err error
+}
+
+func (q stubQuerier) GetContext(ctx context.Context, dest any, query string, args ...any) error {
+	return q.err
+}
+
+func (q stubQuerier) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return nil, q.err
+}
+
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	tp := tracing.NewTracerProvider(sdktrace.WithSampler(sdktrace.AlwaysSample()), sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		_ = tp.Shutdown(context.Background())
+	})
+	return recorder
+}
+
+func TestTraceQuerierSpans(t *testing.T) {
+	recorder := recordSpans(t)
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+
+	failure := errors.New("connection reset")
+	_ = TraceQuerier(stubQuerier{}).GetContext(ctx, nil, "  select * from users where id = $1", "secret-id")
+	_ = TraceQuerier(stubQuerier{err: sql.ErrNoRows}).GetContext(ctx, nil, "SELECT 1")
+	_, _ = TraceQuerier(stubQuerier{err: failure}).ExecContext(ctx, "UPDATE users SET first_name = $1", "secret-name")
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 4 {
+		t.Fatalf("got %d spans, want 4", len(spans))
+	}
+
+	selectSpan, noRowsSpan, updateSpan := spans[0], spans[1], spans[2]
+	if selectSpan.Name() != "SELECT" || updateSpan.Name() != "UPDATE" {
+		t.Errorf("span names = %s, %s, want SELECT, UPDATE", selectSpan.Name(), updateSpan.Name())
+	}
+	for _, span := range []sdktrace.ReadOnlySpan{selectSpan, noRowsSpan, updateSpan} {
+		if span.SpanKind() != trace.SpanKindClient {
+			t.Errorf("%s kind = %s, want client", span.Name(), span.SpanKind())
+		}
+		if span.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("%s is not a child of the request span", span.Name())
+		}
+		for _, attr := range span.Attributes() {
+			if attr.Value.Emit() == "secret-id" || attr.Value.Emit() == "secret-name" {
+				t.Errorf("%s records the query arguments", span.Name())
+			}
+		}
+	}
+
+	if !hasAttribute(selectSpan, semconv.DBQueryTextKey.String("select * from users where id = $1")) {
+		t.Errorf("select span attributes = %v", selectSpan.Attributes())
+	}
+	if noRowsSpan.Status().Code == codes.Error {
+		t.Error("sql.ErrNoRows is recorded as an error")
+	}
+	if updateSpan.Status().Code != codes.Error || updateSpan.Status().Description != failure.Error() {
+		t.Errorf("update span status = %+v, want the error", updateSpan.Status())
+	}
+}
+
+// Queries outside a trace, ex: the outbox relay polling, don't start traces of their own.
+func TestTraceQuerierSkipsQueriesOutsideATrace(t *testing.T) {
+	recorder := recordSpans(t)
+
+	_ = TraceQuerier(stubQuerier{}).GetContext(context.Background(), nil, "SELECT 1")
+
+	if spans := recorder.Ended(); len(spans) != 0 {
+		t.Errorf("got %d spans, want none", len(spans))
+	}
+}
+
+func hasAttribute(span sdktrace.ReadOnlySpan, want attribute.KeyValue) bool {
+	for _, attr := range span.Attributes() {
+		if attr == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,163 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-api-template/internal/libs/logger"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+// Postgres error codes that mean the transaction can be retried from the start.
+const (
+	pgSerializationFailure = "40001"
+	pgDeadlockDetected     = "40P01"
+)
+
+const defaultTxMaxRetries = 3
+const txRetryBaseDelay = 20 * time.Millisecond
+
+// Querier is implemented by both *sqlx.DB and *sqlx.Tx, repositories accept it so they
+// can run on the pool or inside a transaction.
+type Querier interface {
+	sqlx.ExtContext
+	GetContext(ctx context.Context, dest any, query string, args ...any) error
+	SelectContext(ctx context.Context, dest any, query string, args ...any) error
+	NamedExecContext(ctx context.Context, query string, arg any) (sql.Result, error)
+}
+
+type txContextKey struct{}
+
+type txState struct {
+	tx         *sqlx.Tx
+	savepoints int
+}
+
+// TxRunner runs functions in a transaction (unit of work).
+type TxRunner struct {
+	db         *sqlx.DB
+	maxRetries int
+}
+
+func NewTxRunner(db *sqlx.DB) *TxRunner {
+	return &TxRunner{db: db, maxRetries: defaultTxMaxRetries}
+}
+
+// WithTx runs fn in a transaction with the default isolation level. See WithTxOptions.
+func (r *TxRunner) WithTx(ctx context.Context, fn func(ctx context.Context, tx Querier) error) error {
+	return r.WithTxOptions(ctx, nil, fn)
+}
+
+// WithTxOptions commits when fn returns nil and rolls back otherwise (or on panic).
+//
+// The ctx passed to fn carries the transaction: calling WithTx again with it creates a savepoint
+// instead of a new transaction, so a nested failure only rolls back the nested work.
+// Serialization failures and deadlocks of the outermost transaction are retried with backoff,
+// so fn must be safe to run more than once.
+func (r *TxRunner) WithTxOptions(ctx context.Context, opts *sql.TxOptions, fn func(ctx context.Context, tx Querier) error) error {
+	if state, ok := ctx.Value(txContextKey{}).(*txState); ok {
+		return withSavepoint(ctx, state, fn)
+	}
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = r.runTx(ctx, opts, fn)
+		if err == nil || !IsRetryableTxError(err) || attempt >= r.maxRetries {
+			return err
+		}
+
+		delay := txRetryBaseDelay * time.Duration(1<<attempt)
+		delay += time.Duration(rand.Int64N(int64(delay)))
+		logger.FromContext(ctx).WithError(err).WithFields(logrus.Fields{
+			"attempt": attempt + 1,
+			"delay":   delay,
+		}).Warn("Retrying transaction")
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (r *TxRunner) runTx(ctx context.Context, opts *sql.TxOptions, fn func(ctx context.Context, tx Querier) error) (err error) {
+	tx, err := r.db.BeginTxx(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if err = fn(context.WithValue(ctx, txContextKey{}, &txState{tx: tx}), TraceQuerier(tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func withSavepoint(ctx context.Context, state *txState, fn func(ctx context.Context, tx Querier) error) (err error) {
+	state.savepoints++
+	savepoint := fmt.Sprintf("sp_%d", state.savepoints)
+
+	if _, err := state.tx.ExecContext(ctx, "SAVEPOINT "+savepoint); err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_, _ = state.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+savepoint)
+			panic(p)
+		}
+		if err != nil {
+			if _, rbErr := state.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+savepoint); rbErr != nil {
+				err = errors.Join(err, rbErr)
+			}
+		}
+	}()
+
+	if err = fn(ctx, TraceQuerier(state.tx)); err != nil {
+		return err
+	}
+	_, err = state.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+savepoint)
+	return err
+}
+
+// TxFromContext returns the transaction started by WithTx, if ctx carries one.
+func TxFromContext(ctx context.Context) (*sqlx.Tx, bool) {
+	state, ok := ctx.Value(txContextKey{}).(*txState)
+	if !ok {
+		return nil, false
+	}
+	return state.tx, true
+}
+
+// QuerierFromContext returns the transaction carried by ctx or db when there is none.
+func QuerierFromContext(ctx context.Context, db Querier) Querier {
+	if tx, ok := TxFromContext(ctx); ok {
+		return TraceQuerier(tx)
+	}
+	return db
+}
+
+// IsRetryableTxError reports if err is a serialization failure or a deadlock.
+func IsRetryableTxError(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code == pgSerializationFailure || pqErr.Code == pgDeadlockDetected
+}
@@ -0,0 +1,86 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event is a published event contract. EventType is also the routing key of the event.
+// A breaking change of the payload gets a new EventVersion and an upcaster from the previous one.
+type Event interface {
+	EventType() string
+	EventVersion() int
+}
+
+// Envelope is the JSON body of every published event.
+type Envelope[T Event] struct {
+	ID         string    `json:"id"`
+	EventType  string    `json:"eventType"`
+	Version    int       `json:"version"`
+	OccurredAt time.Time `json:"occurredAt"`
+	Data       T         `json:"data"`
+}
+
+var (
+	ErrUnexpectedEventType = errors.New("unexpected event type")
+	// ErrUnknownEventVersion is returned for versions the consumer can't upcast, ex: a newer producer.
+	ErrUnknownEventVersion = errors.New("unknown event version")
+)
+
+// NewEnvelope wraps event with a new ID and the current time.
+func NewEnvelope[T Event](event T) Envelope[T] {
+	return Envelope[T]{
+		ID:         uuid.NewString(),
+		EventType:  event.EventType(),
+		Version:    event.EventVersion(),
+		OccurredAt: time.Now().UTC(),
+		Data:       event,
+	}
+}
+
+// rawEnvelope is an envelope whose data was not decoded yet, it may be of an older version.
+type rawEnvelope struct {
+	ID         string          `json:"id"`
+	EventType  string          `json:"eventType"`
+	Version    int             `json:"version"`
+	OccurredAt time.Time       `json:"occurredAt"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// Decode decodes body into the envelope of T, upcasting its data from older versions with the registered
+// upcasters. Bodies published before the envelope existed are decoded as version 1 of T.
+func Decode[T Event](body []byte) (Envelope[T], error) {
+	var zero T
+	eventType, version := zero.EventType(), zero.EventVersion()
+
+	var raw rawEnvelope
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return Envelope[T]{}, err
+	}
+	if raw.EventType == "" {
+		raw = rawEnvelope{EventType: eventType, Version: 1, Data: body}
+	}
+	if raw.EventType != eventType {
+		return Envelope[T]{}, fmt.Errorf("%w: got %s, want %s", ErrUnexpectedEventType, raw.EventType, eventType)
+	}
+
+	data, err := upcast(eventType, raw.Version, version, raw.Data)
+	if err != nil {
+		return Envelope[T]{}, err
+	}
+
+	envelope := Envelope[T]{
+		ID:         raw.ID,
+		EventType:  raw.EventType,
+		Version:    version,
+		OccurredAt: raw.OccurredAt,
+	}
+	if err := json.Unmarshal(data, &envelope.Data); err != nil {
+		return Envelope[T]{}, fmt.Errorf("decode %s v%d: %w", eventType, version, err)
+	}
+	return envelope, nil
+}
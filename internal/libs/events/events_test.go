@@ -0,0 +1,183 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testNameChanged is at v3: v1 had "name", v2 split it into "firstName" and "lastName", v3 renamed "lastName" to "familyName".
+type testNameChanged struct {
+	UserID     string `json:"userId"`
+	FirstName  string `json:"firstName"`
+	FamilyName string `json:"familyName"`
+}
+
+func (testNameChanged) EventType() string { return "test.name_changed" }
+func (testNameChanged) EventVersion() int { return 3 }
+
+// testNotRegistered is at v2 without upcasters.
+type testNotRegistered struct {
+	ID string `json:"id"`
+}
+
+func (testNotRegistered) EventType() string { return "test.not_registered" }
+func (testNotRegistered) EventVersion() int { return 2 }
+
+// Registered once for the package, a second Register panics.
+func init() {
+	Register[testNameChanged](map[int]Upcaster{
+		1: func(data json.RawMessage) (json.RawMessage, error) {
+			var v1 struct {
+				UserID string `json:"userId"`
+				Name   string `json:"name"`
+			}
+			if err := json.Unmarshal(data, &v1); err != nil {
+				return nil, err
+			}
+			first, last, _ := strings.Cut(v1.Name, " ")
+			return json.Marshal(map[string]string{"userId": v1.UserID, "firstName": first, "lastName": last})
+		},
+		2: func(data json.RawMessage) (json.RawMessage, error) {
+			var v2 map[string]string
+			if err := json.Unmarshal(data, &v2); err != nil {
+				return nil, err
+			}
+			v2["familyName"] = v2["lastName"]
+			delete(v2, "lastName")
+			return json.Marshal(v2)
+		},
+	})
+}
+
+var wantNameChanged = testNameChanged{UserID: "1", FirstName: "Ada", FamilyName: "Lovelace"}
+
+func TestDecodeCurrentVersion(t *testing.T) {
+	envelope := NewEnvelope(wantNameChanged)
+	body, err := json.Marshal(envelope)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	decoded, err := Decode[testNameChanged](body)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if decoded.Data != wantNameChanged || decoded.ID != envelope.ID || decoded.Version != 3 {
+		t.Errorf("decoded %+v, want %+v", decoded, envelope)
+	}
+	if !decoded.OccurredAt.Equal(envelope.OccurredAt) {
+		t.Errorf("occurredAt = %s, want %s", decoded.OccurredAt, envelope.OccurredAt)
+	}
+}
+
+// Bodies published before the envelope existed are v1 data, upcast through every version.
+func TestDecodeV1BodyWithoutEnvelope(t *testing.T) {
+	decoded, err := Decode[testNameChanged]([]byte(`{"userId":"1","name":"Ada Lovelace"}`))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if decoded.Data != wantNameChanged {
+		t.Errorf("data = %+v, want %+v", decoded.Data, wantNameChanged)
+	}
+	if decoded.Version != 3 || decoded.EventType != "test.name_changed" {
+		t.Errorf("decoded as %s v%d", decoded.EventType, decoded.Version)
+	}
+}
+
+func TestDecodeChainsUpcasters(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "from v1", body: `{"id":"e1","eventType":"test.name_changed","version":1,"occurredAt":"2026-01-02T03:04:05Z","data":{"userId":"1","name":"Ada Lovelace"}}`},
+		{name: "from v2", body: `{"id":"e1","eventType":"test.name_changed","version":2,"occurredAt":"2026-01-02T03:04:05Z","data":{"userId":"1","firstName":"Ada","lastName":"Lovelace"}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoded, err := Decode[testNameChanged]([]byte(tt.body))
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if decoded.Data != wantNameChanged {
+				t.Errorf("data = %+v, want %+v", decoded.Data, wantNameChanged)
+			}
+			if decoded.ID != "e1" || !decoded.OccurredAt.Equal(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)) {
+				t.Errorf("envelope = %+v, want the original id and time", decoded)
+			}
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		decode func() error
+		want   error
+	}{
+		{
+			name: "newer version",
+			decode: func() error {
+				_, err := Decode[testNameChanged]([]byte(`{"eventType":"test.name_changed","version":4,"data":{}}`))
+				return err
+			},
+			want: ErrUnknownEventVersion,
+		},
+		{
+			name: "version 0",
+			decode: func() error {
+				_, err := Decode[testNameChanged]([]byte(`{"eventType":"test.name_changed","version":0,"data":{}}`))
+				return err
+			},
+			want: ErrUnknownEventVersion,
+		},
+		{
+			name: "missing upcaster",
+			decode: func() error {
+				_, err := Decode[testNotRegistered]([]byte(`{"eventType":"test.not_registered","version":1,"data":{"id":"1"}}`))
+				return err
+			},
+			want: ErrUnknownEventVersion,
+		},
+		{
+			name: "other event type",
+			decode: func() error {
+				_, err := Decode[testNameChanged]([]byte(`{"eventType":"test.other","version":3,"data":{}}`))
+				return err
+			},
+			want: ErrUnexpectedEventType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.decode(); !errors.Is(err, tt.want) {
+				t.Errorf("Decode error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegisterTwicePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("registering test.name_changed twice didn't panic")
+		}
+	}()
+	Register[testNameChanged](nil)
+}
+
+func TestRegistered(t *testing.T) {
+	for _, registered := range Registered() {
+		if registered.EventType == "test.name_changed" {
+			if registered.Version != 3 {
+				t.Errorf("test.name_changed is registered at v%d, want v3", registered.Version)
+			}
+			return
+		}
+	}
+	t.Error("test.name_changed is not registered")
+}
@@ -0,0 +1,49 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"go-api-template/internal/libs/correlation"
+	"go-api-template/internal/libs/queue"
+	"strconv"
+
+	"go.opentelemetry.io/otel"
+)
+
+// HeaderEventVersion carries the event version, so consumers can route on it without decoding the body.
+const HeaderEventVersion = "x-event-version"
+
+// Publish sends event to exchange, with its type as the routing key. Services write events to the outbox
+// instead (see Message), this is for publishers outside a database transaction.
+func Publish[T Event](ctx context.Context, publisher queue.Publisher, exchange string, event T) error {
+	msg, err := Message(ctx, exchange, NewEnvelope(event))
+	if err != nil {
+		return err
+	}
+	return publisher.Publish(ctx, msg)
+}
+
+// Message builds the queue message of the envelope, correlated with the request or message handled by ctx.
+// The trace context of ctx goes in the headers, so an event relayed from the outbox later stays in the trace.
+func Message[T Event](ctx context.Context, exchange string, envelope Envelope[T]) (queue.Message, error) {
+	body, err := json.Marshal(envelope)
+	if err != nil {
+		return queue.Message{}, err
+	}
+
+	headers := map[string]any{HeaderEventVersion: strconv.Itoa(envelope.Version)}
+	otel.GetTextMapPropagator().Inject(ctx, queue.HeaderCarrier(headers))
+
+	return queue.Message{
+		Exchange:      exchange,
+		RoutingKey:    envelope.EventType,
+		Body:          body,
+		ContentType:   "application/json",
+		MessageID:     envelope.ID,
+		CorrelationID: correlation.CorrelationID(ctx),
+		CausationID:   correlation.CausationID(ctx),
+		Headers:       headers,
+		Timestamp:     envelope.OccurredAt,
+		Type:          envelope.EventType,
+	}, nil
+}
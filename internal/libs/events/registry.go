@@ -0,0 +1,81 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Upcaster converts the data of an event version to the next version.
+type Upcaster func(data json.RawMessage) (json.RawMessage, error)
+
+// Type describes a registered event.
+type Type struct {
+	EventType string
+	Version   int
+}
+
+type registration struct {
+	version int
+	// upcasters are keyed by the version they convert from.
+	upcasters map[int]Upcaster
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]registration{}
+)
+
+// Register adds T to the registry with the upcasters from its older versions, keyed by the version they
+// convert from: upcasters[1] converts v1 data to v2. Registering the same event type twice panics.
+func Register[T Event](upcasters map[int]Upcaster) {
+	var zero T
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, ok := registry[zero.EventType()]; ok {
+		panic(fmt.Sprintf("event %s registered twice", zero.EventType()))
+	}
+	registry[zero.EventType()] = registration{version: zero.EventVersion(), upcasters: upcasters}
+}
+
+// Registered returns the registered events, sorted by type.
+func Registered() []Type {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	types := make([]Type, 0, len(registry))
+	for eventType, r := range registry {
+		types = append(types, Type{EventType: eventType, Version: r.version})
+	}
+	sort.Slice(types, func(i, j int) bool {
+		return types[i].EventType < types[j].EventType
+	})
+	return types
+}
+
+// upcast converts data from version from to version to, one version at a time.
+func upcast(eventType string, from int, to int, data json.RawMessage) (json.RawMessage, error) {
+	if from > to || from < 1 {
+		return nil, fmt.Errorf("%w: %s v%d, consumer knows up to v%d", ErrUnknownEventVersion, eventType, from, to)
+	}
+
+	registryMu.RLock()
+	upcasters := registry[eventType].upcasters
+	registryMu.RUnlock()
+
+	for version := from; version < to; version++ {
+		up, ok := upcasters[version]
+		if !ok {
+			return nil, fmt.Errorf("%w: no upcaster for %s v%d", ErrUnknownEventVersion, eventType, version)
+		}
+
+		var err error
+		if data, err = up(data); err != nil {
+			return nil, fmt.Errorf("upcast %s v%d: %w", eventType, version, err)
+		}
+	}
+	return data, nil
+}
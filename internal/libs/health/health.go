@@ -0,0 +1,112 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Statuses of a Report and of each check.
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+	StatusDraining    = "draining"
+)
+
+// Check returns an error when the dependency can't be used. It must respect ctx.
+type Check func(ctx context.Context) error
+
+// Health runs the readiness checks of the dependencies and tracks the draining state.
+type Health struct {
+	timeout  time.Duration
+	checks   []namedCheck
+	draining atomic.Bool
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Report is the readiness of the app with the result of every check.
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+type CheckResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	// DurationMs is how long the check took, in milliseconds.
+	DurationMs int64 `json:"durationMs"`
+}
+
+// NewHealth returns a Health whose checks each get timeout to answer (default 2s).
+func NewHealth(timeout time.Duration) *Health {
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	return &Health{timeout: timeout}
+}
+
+// Register adds a readiness check, name is its key in the report. Register every check before serving.
+func (h *Health) Register(name string, check Check) {
+	h.checks = append(h.checks, namedCheck{name: name, check: check})
+}
+
+// SetDraining marks the app as shutting down, readiness fails from then on so the load balancer
+// stops sending requests while the in-flight ones finish.
+func (h *Health) SetDraining() {
+	h.draining.Store(true)
+}
+
+func (h *Health) Draining() bool {
+	return h.draining.Load()
+}
+
+// Ready runs the checks concurrently. The report is ok when every check passed and the app is not draining.
+func (h *Health) Ready(ctx context.Context) Report {
+	results := make([]CheckResult, len(h.checks))
+
+	var wg sync.WaitGroup
+	for i, c := range h.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = h.run(ctx, c.check)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]CheckResult, len(h.checks))}
+	for i, c := range h.checks {
+		report.Checks[c.name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusUnavailable
+		}
+	}
+	if h.Draining() {
+		report.Status = StatusDraining
+	}
+	return report
+}
+
+func (h *Health) run(ctx context.Context, check Check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(ctx)
+	// A check that ignores ctx is still reported as failed once the timeout is over.
+	if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+
+	result := CheckResult{Status: StatusOK, DurationMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		result.Status = StatusUnavailable
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,56 @@
+package logger
+
+import (
+	"context"
+	"go-api-template/config"
+	"go-api-template/internal/libs/correlation"
+	"os"
+
+	"github.com/sirupsen/logrus"
+)
+
+type loggerContextKey struct{}
+
+// Setup configures the global logrus logger from config: JSON in PROD so log collectors can parse the
+// fields, text in DEV, and the level of LOG_LEVEL (info when empty or unknown).
+func Setup() {
+	if config.CONFIG.Env == config.PROD_ENV {
+		logrus.SetFormatter(&logrus.JSONFormatter{})
+	} else {
+		logrus.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
+	}
+	logrus.SetOutput(os.Stdout)
+
+	level, err := logrus.ParseLevel(config.CONFIG.LogLevel)
+	if err != nil {
+		level = logrus.InfoLevel
+		if config.CONFIG.LogLevel != "" {
+			logrus.WithField("level", config.CONFIG.LogLevel).Warn("Unknown LOG_LEVEL, using info")
+		}
+	}
+	logrus.SetLevel(level)
+}
+
+// WithLogger stores the logger of the request (or message) being handled.
+func WithLogger(ctx context.Context, log *logrus.Entry) context.Context {
+	return context.WithValue(ctx, loggerContextKey{}, log)
+}
+
+// WithFields adds fields to the logger of ctx, ex: the user ID once the request is authenticated.
+func WithFields(ctx context.Context, fields logrus.Fields) context.Context {
+	return WithLogger(ctx, FromContext(ctx).WithFields(fields))
+}
+
+// FromContext returns the logger stored in ctx. Without one, it returns the global logger with the
+// correlation ID of ctx, if any.
+func FromContext(ctx context.Context) *logrus.Entry {
+	if log, ok := ctx.Value(loggerContextKey{}).(*logrus.Entry); ok && log != nil {
+		return log
+	}
+
+	log := logrus.NewEntry(logrus.StandardLogger())
+	if correlationID := correlation.CorrelationID(ctx); correlationID != "" {
+		log = log.WithField("correlationId", correlationID)
+	}
+	return log
+}
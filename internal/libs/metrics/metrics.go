@@ -0,0 +1,135 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"go-api-template/internal/libs/queue"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics is the registry of the app metrics, with the Go runtime and process metrics. Each server
+// has its own, so several can live in one process (ex: tests) without clashing. It is served by Handler.
+type Metrics struct {
+	Registry *prometheus.Registry
+
+	HTTPRequests         *prometheus.CounterVec
+	HTTPRequestDuration  *prometheus.HistogramVec
+	QueueHandlerDuration *prometheus.HistogramVec
+}
+
+func New() *Metrics {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	factory := promauto.With(registry)
+	return &Metrics{
+		Registry: registry,
+
+		HTTPRequests: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests served, by method, route pattern and status.",
+		}, []string{"method", "route", "status"}),
+
+		HTTPRequestDuration: factory.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Latency of the HTTP requests, by method, route pattern and status.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+
+		QueueHandlerDuration: factory.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "queue_handler_duration_seconds",
+			Help:    "Duration of the queue handlers, by routing key and result (success or error).",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"routing_key", "result"}),
+	}
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{Registry: m.Registry})
+}
+
+// RegisterBuildInfo exposes app_build_info, always 1, labelled with the version and environment.
+func (m *Metrics) RegisterBuildInfo(version string, env string) {
+	m.Registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name:        "app_build_info",
+		Help:        "Build information of the running app.",
+		ConstLabels: prometheus.Labels{"version": version, "env": env},
+	}, func() float64 { return 1 }))
+}
+
+// RegisterCounterFunc exposes a counter read from value on every scrape, ex: the counters of a Stats method.
+func (m *Metrics) RegisterCounterFunc(name string, help string, value func() float64) {
+	m.Registry.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{Name: name, Help: help}, value))
+}
+
+// RegisterGaugeFunc exposes a gauge read from value on every scrape.
+func (m *Metrics) RegisterGaugeFunc(name string, help string, value func() float64) {
+	m.Registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{Name: name, Help: help}, value))
+}
+
+// QueueHandlerObserver returns the observer recording the handler durations in QueueHandlerDuration,
+// see queueTransport.MetricsMiddleware.
+func (m *Metrics) QueueHandlerObserver() QueueHandlerObserver {
+	return QueueHandlerObserver{duration: m.QueueHandlerDuration}
+}
+
+type QueueHandlerObserver struct {
+	duration *prometheus.HistogramVec
+}
+
+func (o QueueHandlerObserver) ObserveHandler(routingKey queue.RoutingKey, duration time.Duration, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	o.duration.WithLabelValues(string(routingKey), result).Observe(duration.Seconds())
+}
+
+// RegisterDBStats exposes the connection pool stats of db (sql.DB.Stats), labelled with name.
+func (m *Metrics) RegisterDBStats(db *sql.DB, name string) {
+	m.Registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// RegisterQueueLag exposes queue_consumer_lag_messages, the messages waiting in each queue, read from
+// reporter on every scrape. Queues the broker can't report on are left out of the scrape.
+func (m *Metrics) RegisterQueueLag(reporter queue.LagReporter, queueNames []string) {
+	m.Registry.MustRegister(&queueLagCollector{reporter: reporter, queueNames: queueNames})
+}
+
+var queueLagDesc = prometheus.NewDesc(
+	"queue_consumer_lag_messages",
+	"Messages waiting to be delivered to the consumers of the queue.",
+	[]string{"queue"}, nil,
+)
+
+type queueLagCollector struct {
+	reporter   queue.LagReporter
+	queueNames []string
+}
+
+func (c *queueLagCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- queueLagDesc
+}
+
+func (c *queueLagCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	for _, name := range c.queueNames {
+		lag, err := c.reporter.QueueLag(ctx, name)
+		if err != nil {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(queueLagDesc, prometheus.GaugeValue, float64(lag), name)
+	}
+}
@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Every server registers the same metrics, two registries must not clash.
+func TestNewRegistriesAreIndependent(t *testing.T) {
+	first := New()
+	second := New()
+	for _, m := range []*Metrics{first, second} {
+		m.RegisterBuildInfo("1.0.0", "test")
+		m.RegisterCounterFunc("outbox_published_total", "Events published by the outbox relay.", func() float64 { return 1 })
+	}
+
+	first.QueueHandlerObserver().ObserveHandler("users.created", time.Millisecond, errors.New("boom"))
+
+	firstBody := scrape(t, first)
+	if !strings.Contains(firstBody, `queue_handler_duration_seconds_count{result="error",routing_key="users.created"} 1`) {
+		t.Errorf("the first registry misses the handler duration:\n%s", firstBody)
+	}
+	secondBody := scrape(t, second)
+	if strings.Contains(secondBody, "queue_handler_duration_seconds_count") {
+		t.Errorf("the handler duration leaked to the second registry:\n%s", secondBody)
+	}
+	if !strings.Contains(secondBody, "outbox_published_total 1") {
+		t.Errorf("the second registry misses its counter:\n%s", secondBody)
+	}
+}
+
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("scrape status = %d", rec.Code)
+	}
+	return rec.Body.String()
+}
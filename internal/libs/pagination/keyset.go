@@ -0,0 +1,60 @@
+package pagination
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Fragment holds the SQL pieces a sqlx repository appends to its list query.
+type Fragment struct {
+	// Where is empty for the first page. It only references columns from Options.SortableFields.
+	Where       string
+	OrderBy     string
+	LimitOffset string
+	Args        []any
+}
+
+// SQL builds the keyset (or offset) SQL fragments. argStart is the index of the first
+// placeholder so the fragment can be combined with other query args, ex: $1 when the
+// query has no other args.
+//
+// The keyset condition is expanded, ex: for "created_at DESC, id ASC" it becomes
+// (created_at < $1 OR (created_at = $1 AND id > $2)), which works for mixed directions.
+func (p Params) SQL(argStart int) Fragment {
+	orderBy := make([]string, 0, len(p.Sort))
+	for _, s := range p.Sort {
+		direction := "ASC"
+		if s.Desc {
+			direction = "DESC"
+		}
+		orderBy = append(orderBy, s.Column+" "+direction)
+	}
+
+	fragment := Fragment{
+		OrderBy: strings.Join(orderBy, ", "),
+		// One extra row tells if there is a next page.
+		LimitOffset: fmt.Sprintf("LIMIT %d OFFSET %d", p.Limit+1, p.Offset),
+	}
+
+	if p.cursor == nil {
+		return fragment
+	}
+
+	ors := make([]string, 0, len(p.Sort))
+	for i, s := range p.Sort {
+		ands := make([]string, 0, i+1)
+		for j := range i {
+			ands = append(ands, fmt.Sprintf("%s = $%d", p.Sort[j].Column, argStart+j))
+		}
+		operator := ">"
+		if s.Desc {
+			operator = "<"
+		}
+		ands = append(ands, fmt.Sprintf("%s %s $%d", s.Column, operator, argStart+i))
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+
+	fragment.Where = "(" + strings.Join(ors, " OR ") + ")"
+	fragment.Args = append(fragment.Args, p.cursor.Values...)
+	return fragment
+}
@@ -0,0 +1,77 @@
+package pagination
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestKeysetSQL(t *testing.T) {
+	tests := []struct {
+		name      string
+		sort      []SortField
+		argStart  int
+		wantWhere string
+		wantOrder string
+	}{
+		{
+			name:      "ascending with id tie-break",
+			sort:      []SortField{{Column: "created_at"}, {Column: "id"}},
+			argStart:  1,
+			wantWhere: "((created_at > $1) OR (created_at = $1 AND id > $2))",
+			wantOrder: "created_at ASC, id ASC",
+		},
+		{
+			name:      "descending with id tie-break",
+			sort:      []SortField{{Column: "created_at", Desc: true}, {Column: "id"}},
+			argStart:  1,
+			wantWhere: "((created_at < $1) OR (created_at = $1 AND id > $2))",
+			wantOrder: "created_at DESC, id ASC",
+		},
+		{
+			name:      "mixed directions after other args",
+			sort:      []SortField{{Column: "email"}, {Column: "created_at", Desc: true}, {Column: "id"}},
+			argStart:  3,
+			wantWhere: "((email > $3) OR (email = $3 AND created_at < $4) OR (email = $3 AND created_at = $4 AND id > $5))",
+			wantOrder: "email ASC, created_at DESC, id ASC",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := make([]any, len(tt.sort))
+			for i := range values {
+				values[i] = i
+			}
+			params := Params{Limit: 10, Sort: tt.sort, cursor: &cursor{Sort: sortSignature(tt.sort), Values: values}}
+
+			fragment := params.SQL(tt.argStart)
+			if fragment.Where != tt.wantWhere {
+				t.Errorf("where = %s\nwant    %s", fragment.Where, tt.wantWhere)
+			}
+			if fragment.OrderBy != tt.wantOrder {
+				t.Errorf("order by = %s, want %s", fragment.OrderBy, tt.wantOrder)
+			}
+			if !slices.Equal(fragment.Args, values) {
+				t.Errorf("args = %v, want %v", fragment.Args, values)
+			}
+			// One extra row tells if there is a next page.
+			if fragment.LimitOffset != "LIMIT 11 OFFSET 0" {
+				t.Errorf("limit = %s", fragment.LimitOffset)
+			}
+		})
+	}
+}
+
+func TestFirstPageHasNoWhere(t *testing.T) {
+	params := Params{Limit: 10, Sort: []SortField{{Column: "created_at"}, {Column: "id"}}}
+	if fragment := params.SQL(1); fragment.Where != "" || len(fragment.Args) != 0 {
+		t.Errorf("first page fragment = %+v", fragment)
+	}
+}
+
+func TestOffsetSQL(t *testing.T) {
+	params := Params{Limit: 10, Offset: 20, Sort: []SortField{{Column: "id"}}, useOffset: true}
+	if fragment := params.SQL(1); fragment.LimitOffset != "LIMIT 11 OFFSET 20" || fragment.Where != "" {
+		t.Errorf("offset fragment = %+v", fragment)
+	}
+}
@@ -0,0 +1,90 @@
+package pagination
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Page is the pagination metadata rendered in the meta of SuccessfulResponse.
+type Page struct {
+	Limit      int    `json:"limit"`
+	Offset     *int   `json:"offset,omitempty"`
+	NextCursor string `json:"nextCursor,omitempty"`
+	Total      *int   `json:"total,omitempty"`
+
+	hasNext bool
+}
+
+// Paginate drops the extra row fetched by Params.SQL and builds the page metadata.
+// columnValue returns the value of a sort column for an item, it is used to build the next cursor.
+func Paginate[T any](items []T, params Params, columnValue func(item T, column string) any) ([]T, Page, error) {
+	page := Page{Limit: params.Limit}
+	if !params.IsKeyset() {
+		offset := params.Offset
+		page.Offset = &offset
+	}
+
+	if len(items) <= params.Limit {
+		return items, page, nil
+	}
+
+	items = items[:params.Limit]
+	page.hasNext = true
+
+	if params.IsKeyset() {
+		last := items[len(items)-1]
+		values := make([]any, 0, len(params.Sort))
+		for _, s := range params.Sort {
+			values = append(values, columnValue(last, s.Column))
+		}
+
+		nextCursor, err := encodeCursor(cursor{Sort: sortSignature(params.Sort), Values: values}, params.cursorSecret)
+		if err != nil {
+			return nil, Page{}, err
+		}
+		page.NextCursor = nextCursor
+	}
+
+	return items, page, nil
+}
+
+// WithTotal sets the total number of items of the list.
+func (p Page) WithTotal(total int) Page {
+	p.Total = &total
+	return p
+}
+
+// LinkHeader builds the RFC 8288 Link header value (first, prev, next) for the request URL.
+func (p Page) LinkHeader(requestURL *url.URL) string {
+	var links []string
+
+	link := func(rel string, set map[string]string) {
+		u := *requestURL
+		query := u.Query()
+		query.Del("cursor")
+		query.Del("offset")
+		for k, v := range set {
+			query.Set(k, v)
+		}
+		u.RawQuery = query.Encode()
+		links = append(links, fmt.Sprintf(`<%s>; rel="%s"`, u.RequestURI(), rel))
+	}
+
+	link("first", map[string]string{})
+
+	if p.Offset != nil && *p.Offset > 0 {
+		link("prev", map[string]string{"offset": strconv.Itoa(max(*p.Offset-p.Limit, 0))})
+	}
+
+	if p.hasNext {
+		if p.NextCursor != "" {
+			link("next", map[string]string{"cursor": p.NextCursor})
+		} else if p.Offset != nil {
+			link("next", map[string]string{"offset": strconv.Itoa(*p.Offset + p.Limit)})
+		}
+	}
+
+	return strings.Join(links, ", ")
+}
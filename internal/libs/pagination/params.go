@@ -0,0 +1,218 @@
+package pagination
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	errs "go-api-template/internal/errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const DefaultLimit = 20
+const DefaultMaxLimit = 100
+
+// TiebreakerColumn is always appended to the sort so keyset pages are stable.
+const TiebreakerColumn = "id"
+
+type SortField struct {
+	Column string
+	Desc   bool
+}
+
+// Options describe what a list endpoint accepts.
+// SortableFields maps the names accepted in the sort query param to their SQL column.
+// CursorSecret signs the cursors (HMAC-SHA256), a cursor the client changed is rejected.
+type Options struct {
+	DefaultLimit   int
+	MaxLimit       int
+	SortableFields map[string]string
+	DefaultSort    []SortField
+	CursorSecret   []byte
+}
+
+// Params are the validated pagination query params of a list request.
+type Params struct {
+	Limit  int
+	Offset int
+	Sort   []SortField
+
+	// cursor holds the values of the last row of the previous page, one per Sort column.
+	cursor       *cursor
+	cursorSecret []byte
+	useOffset    bool
+}
+
+type cursor struct {
+	Sort   string `json:"s"`
+	Values []any  `json:"v"`
+}
+
+// IsKeyset reports if the request uses cursor (keyset) pagination instead of offset pagination.
+func (p Params) IsKeyset() bool {
+	return !p.useOffset
+}
+
+// ParseParams reads limit, cursor, offset and sort from the query string.
+// Invalid values are returned as a 400 errs.HTTPError.
+func ParseParams(r *http.Request, opts Options) (Params, error) {
+	query := r.URL.Query()
+	params := Params{Limit: opts.defaultLimit(), cursorSecret: opts.CursorSecret}
+
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > opts.maxLimit() {
+			return Params{}, errs.NewInvalidPaginationParamsError(fmt.Sprintf("limit must be a number between 1 and %d", opts.maxLimit()))
+		}
+		params.Limit = limit
+	}
+
+	if raw := query.Get("offset"); raw != "" {
+		offset, err := strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			return Params{}, errs.NewInvalidPaginationParamsError("offset must be a positive number")
+		}
+		params.Offset = offset
+		params.useOffset = true
+	}
+
+	sort, err := parseSort(query.Get("sort"), opts)
+	if err != nil {
+		return Params{}, err
+	}
+	params.Sort = sort
+
+	if raw := query.Get("cursor"); raw != "" {
+		if params.useOffset {
+			return Params{}, errs.NewInvalidPaginationParamsError("cursor and offset can't be used together")
+		}
+		c, err := decodeCursor(raw, params.cursorSecret)
+		if err != nil || c.Sort != sortSignature(params.Sort) || !c.valid(params.Sort) {
+			return Params{}, errs.NewInvalidPaginationParamsError("cursor is invalid or was created with a different sort")
+		}
+		params.cursor = &c
+	}
+
+	return params, nil
+}
+
+func parseSort(raw string, opts Options) ([]SortField, error) {
+	var sort []SortField
+	if raw == "" {
+		sort = append(sort, opts.DefaultSort...)
+	}
+
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		desc := strings.HasPrefix(part, "-")
+		name := strings.TrimLeft(part, "+-")
+		column, ok := opts.SortableFields[name]
+		if !ok {
+			return nil, errs.NewInvalidPaginationParamsError(fmt.Sprintf("can't sort by %q", name))
+		}
+		sort = append(sort, SortField{Column: column, Desc: desc})
+	}
+
+	for _, s := range sort {
+		if s.Column == TiebreakerColumn {
+			return sort, nil
+		}
+	}
+	return append(sort, SortField{Column: TiebreakerColumn}), nil
+}
+
+func (o Options) defaultLimit() int {
+	if o.DefaultLimit <= 0 {
+		return DefaultLimit
+	}
+	return o.DefaultLimit
+}
+
+func (o Options) maxLimit() int {
+	if o.MaxLimit <= 0 {
+		return DefaultMaxLimit
+	}
+	return o.MaxLimit
+}
+
+func sortSignature(sort []SortField) string {
+	parts := make([]string, 0, len(sort))
+	for _, s := range sort {
+		if s.Desc {
+			parts = append(parts, "-"+s.Column)
+		} else {
+			parts = append(parts, s.Column)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+var errCursorSignature = errors.New("cursor signature doesn't match")
+
+// encodeCursor returns the base64 JSON of c, followed by "." and its signature when secret is set.
+func encodeCursor(c cursor, secret []byte) (string, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(b)
+	if len(secret) == 0 {
+		return payload, nil
+	}
+	return payload + "." + base64.RawURLEncoding.EncodeToString(cursorSignature(payload, secret)), nil
+}
+
+func decodeCursor(raw string, secret []byte) (cursor, error) {
+	payload := raw
+	if len(secret) > 0 {
+		var signature string
+		var ok bool
+		payload, signature, ok = strings.Cut(raw, ".")
+		if !ok {
+			return cursor{}, errCursorSignature
+		}
+		sig, err := base64.RawURLEncoding.DecodeString(signature)
+		if err != nil || !hmac.Equal(sig, cursorSignature(payload, secret)) {
+			return cursor{}, errCursorSignature
+		}
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return cursor{}, err
+	}
+	var c cursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return cursor{}, err
+	}
+	return c, nil
+}
+
+func cursorSignature(payload string, secret []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// valid reports if the cursor has one scalar value per sort column. The values are query args,
+// an object or an array would fail in the database instead of being a bad request.
+func (c cursor) valid(sort []SortField) bool {
+	if len(c.Values) != len(sort) {
+		return false
+	}
+	for _, value := range c.Values {
+		switch value.(type) {
+		case string, float64, bool, nil:
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,146 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	errs "go-api-template/internal/errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var testOptions = Options{
+	SortableFields: map[string]string{"createdAt": "created_at", "email": "email"},
+	DefaultSort:    []SortField{{Column: "created_at"}},
+	CursorSecret:   []byte("test-secret"),
+}
+
+type testItem struct {
+	ID        string
+	CreatedAt string
+}
+
+func testItemValue(item testItem, column string) any {
+	if column == "created_at" {
+		return item.CreatedAt
+	}
+	return item.ID
+}
+
+func parseQuery(t *testing.T, query url.Values, opts Options) (Params, error) {
+	t.Helper()
+	return ParseParams(httptest.NewRequest(http.MethodGet, "/users?"+query.Encode(), nil), opts)
+}
+
+// nextCursor returns the cursor of a page ending with last, for the default sort.
+func nextCursor(t *testing.T, last testItem) string {
+	t.Helper()
+	params, err := parseQuery(t, url.Values{"limit": {"1"}}, testOptions)
+	if err != nil {
+		t.Fatalf("ParseParams: %v", err)
+	}
+	_, page, err := Paginate([]testItem{last, {ID: "next"}}, params, testItemValue)
+	if err != nil {
+		t.Fatalf("Paginate: %v", err)
+	}
+	if page.NextCursor == "" {
+		t.Fatal("no next cursor")
+	}
+	return page.NextCursor
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	last := testItem{ID: "b8a4b9c4-2f5e-4b8e-9a43-2b3a3f1d0c11", CreatedAt: "2026-01-02T03:04:05Z"}
+	params, err := parseQuery(t, url.Values{"limit": {"1"}, "cursor": {nextCursor(t, last)}}, testOptions)
+	if err != nil {
+		t.Fatalf("ParseParams: %v", err)
+	}
+	if !params.IsKeyset() {
+		t.Error("a cursor request is not keyset")
+	}
+	fragment := params.SQL(1)
+	if want := []any{last.CreatedAt, last.ID}; !slices.Equal(fragment.Args, want) {
+		t.Errorf("args = %v, want %v", fragment.Args, want)
+	}
+}
+
+func TestRejectsInvalidCursors(t *testing.T) {
+	valid := nextCursor(t, testItem{ID: "1", CreatedAt: "2026-01-02T03:04:05Z"})
+	payload, signature, _ := strings.Cut(valid, ".")
+	sign := func(json string) string {
+		payload := base64.RawURLEncoding.EncodeToString([]byte(json))
+		return payload + "." + base64.RawURLEncoding.EncodeToString(cursorSignature(payload, testOptions.CursorSecret))
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+		sort   string
+	}{
+		{name: "not base64", cursor: "%%%"},
+		{name: "unsigned", cursor: payload},
+		{name: "changed payload", cursor: base64.RawURLEncoding.EncodeToString([]byte(`{"s":"created_at,id","v":["2020-01-01",1]}`)) + "." + signature},
+		{name: "other secret", cursor: payload + "." + base64.RawURLEncoding.EncodeToString(cursorSignature(payload, []byte("other")))},
+		{name: "object value", cursor: sign(`{"s":"created_at,id","v":[{"a":1},"1"]}`)},
+		{name: "array value", cursor: sign(`{"s":"created_at,id","v":[["a"],"1"]}`)},
+		{name: "missing value", cursor: sign(`{"s":"created_at,id","v":["2026-01-02T03:04:05Z"]}`)},
+		{name: "extra value", cursor: sign(`{"s":"created_at,id","v":["2026-01-02T03:04:05Z","1","2"]}`)},
+		{name: "other sort", cursor: valid, sort: "-email"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := url.Values{"cursor": {tt.cursor}}
+			if tt.sort != "" {
+				query.Set("sort", tt.sort)
+			}
+			_, err := parseQuery(t, query, testOptions)
+			var httpErr *errs.HTTPError
+			if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusBadRequest || httpErr.ErrorCode != errs.InvalidPaginationParams {
+				t.Errorf("ParseParams error = %v, want a 400 invalid pagination params error", err)
+			}
+		})
+	}
+}
+
+func TestUnsignedCursorsWithoutSecret(t *testing.T) {
+	opts := testOptions
+	opts.CursorSecret = nil
+	cursor, err := encodeCursor(cursor{Sort: "created_at,id", Values: []any{"2026-01-02T03:04:05Z", "1"}}, nil)
+	if err != nil {
+		t.Fatalf("encodeCursor: %v", err)
+	}
+	if strings.Contains(cursor, ".") {
+		t.Errorf("cursor %q is signed without secret", cursor)
+	}
+	if _, err := parseQuery(t, url.Values{"cursor": {cursor}}, opts); err != nil {
+		t.Errorf("ParseParams: %v", err)
+	}
+}
+
+func TestParseSort(t *testing.T) {
+	tests := []struct {
+		sort string
+		want string
+	}{
+		{sort: "", want: "created_at,id"},
+		{sort: "-createdAt", want: "-created_at,id"},
+		{sort: "email,-createdAt", want: "email,-created_at,id"},
+	}
+	for _, tt := range tests {
+		params, err := parseQuery(t, url.Values{"sort": {tt.sort}}, testOptions)
+		if err != nil {
+			t.Fatalf("ParseParams(sort=%q): %v", tt.sort, err)
+		}
+		if got := sortSignature(params.Sort); got != tt.want {
+			t.Errorf("sort=%q gives %q, want %q", tt.sort, got, tt.want)
+		}
+	}
+
+	if _, err := parseQuery(t, url.Values{"sort": {"password"}}, testOptions); err == nil {
+		t.Error("sorting by a field that isn't sortable was accepted")
+	}
+}
@@ -0,0 +1,15 @@
+package queue
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// withJitter returns a random delay between d/2 and d so clients don't retry in lockstep.
+func withJitter(d time.Duration) time.Duration {
+	if d <= 1 {
+		return d
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int64N(int64(half)+1))
+}
@@ -0,0 +1,14 @@
+package queue
+
+import "errors"
+
+var (
+	ErrNotConnected   = errors.New("broker is not connected")
+	ErrPublishNacked  = errors.New("publish was nacked by the broker")
+	ErrUnroutable     = errors.New("message is unroutable")
+	ErrConfirmTimeout = errors.New("timed out waiting for the publish confirm")
+	// ErrNoReplyTo is returned when replying to a message that has no reply-to.
+	ErrNoReplyTo = errors.New("message has no reply-to")
+	// ErrRemote is wrapped by the *RPCError returned when the handler of a request failed.
+	ErrRemote = errors.New("request handler failed")
+)
@@ -0,0 +1,425 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// InMemoryBroker is an in-process broker with the semantics of the RabbitMQ client: topic exchanges,
+// queues bound with patterns, a prefetch limit, and failed messages retried after the queue retry delay
+// then dead-lettered. Messages are lost when the process stops, it is meant for dev mode and tests.
+type InMemoryBroker struct {
+	mu       sync.RWMutex
+	queues   map[string]*inMemoryQueue
+	exchange string
+
+	prefetch  int
+	mandatory bool
+	closed    chan struct{}
+	closeOnce sync.Once
+
+	pending pendingRequests
+
+	consumeStats consumeCounters
+
+	log *logrus.Entry
+}
+
+type InMemoryOptions struct {
+	// Prefetch caps the unacked deliveries of a consumer (default 20).
+	Prefetch int
+	// Mandatory makes Publish fail with ErrUnroutable when no queue is bound to the routing key.
+	Mandatory bool
+}
+
+type inMemoryQueue struct {
+	// mu guards spec too, EnsureTopology can replace it while consumers run.
+	mu          sync.Mutex
+	spec        QueueSpec
+	messages    []Delivery
+	deadLetters []Delivery
+	// ready has a value while messages may be waiting.
+	ready chan struct{}
+}
+
+func NewInMemoryBroker(opts InMemoryOptions) *InMemoryBroker {
+	prefetch := opts.Prefetch
+	if prefetch <= 0 {
+		prefetch = 20
+	}
+	return &InMemoryBroker{
+		queues:    map[string]*inMemoryQueue{},
+		prefetch:  prefetch,
+		mandatory: opts.Mandatory,
+		closed:    make(chan struct{}),
+		log:       logrus.WithField("component", "in-memory-broker"),
+	}
+}
+
+// EnsureTopology creates the queues with their bindings. Queues that already exist keep their messages.
+func (b *InMemoryBroker) EnsureTopology(exchange string, queues []QueueSpec) error {
+	if exchange == "" {
+		return errors.New("exchange is empty")
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.exchange = exchange
+	for _, spec := range queues {
+		if spec.Name == "" {
+			continue
+		}
+		if q, ok := b.queues[spec.Name]; ok {
+			q.setSpec(spec)
+			continue
+		}
+		b.queues[spec.Name] = &inMemoryQueue{spec: spec, ready: make(chan struct{}, 1)}
+	}
+	return nil
+}
+
+// Publish copies msg to every queue bound to its routing key.
+func (b *InMemoryBroker) Publish(ctx context.Context, msg Message) error {
+	return b.publish(ctx, msg, b.mandatory)
+}
+
+// publish fails with ErrUnroutable when mandatory is set and no queue is bound to the routing key.
+func (b *InMemoryBroker) publish(ctx context.Context, msg Message, mandatory bool) error {
+	if msg.Exchange == "" {
+		return errors.New("publish exchange is empty")
+	}
+	if msg.RoutingKey == "" {
+		return errors.New("publish routing key is empty")
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	_, span, msg := startPublishSpan(ctx, "in-memory", withCorrelation(ctx, msg))
+	defer span.End()
+	delivery := toInMemoryDelivery(msg)
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	routed := false
+	if msg.Exchange == b.exchange {
+		for _, q := range b.queues {
+			if q.bound(delivery.RoutingKey) {
+				q.push(delivery)
+				routed = true
+			}
+		}
+	}
+
+	if !routed && mandatory {
+		return fmt.Errorf("%w: %s/%s", ErrUnroutable, msg.Exchange, msg.RoutingKey)
+	}
+	return nil
+}
+
+// Consume handles the messages of queueName with QueueSpec.Concurrency workers until ctx is done,
+// then waits for the in-flight handlers. Like a RabbitMQ channel prefetch, at most prefetch deliveries
+// are unacked (taken from the queue and not handled yet) at a time, whatever the number of workers.
+func (b *InMemoryBroker) Consume(ctx context.Context, queueName string, handler RabbitMQHandler) error {
+	if handler == nil {
+		return errors.New("handler is nil")
+	}
+
+	b.mu.RLock()
+	q, ok := b.queues[queueName]
+	b.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("queue %s does not exist", queueName)
+	}
+
+	workers := max(q.currentSpec().Concurrency, 1)
+	b.log.WithFields(logrus.Fields{
+		"queue":       queueName,
+		"concurrency": workers,
+		"prefetch":    b.prefetch,
+	}).Info("In-memory broker consuming")
+
+	// A worker holds a slot from before it takes a message until the message is acked.
+	unacked := make(chan struct{}, b.prefetch)
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case unacked <- struct{}{}:
+				case <-ctx.Done():
+					return
+				case <-b.closed:
+					return
+				}
+
+				d, ok := q.pop(ctx, b.closed)
+				if !ok {
+					<-unacked
+					return
+				}
+				// In-flight handlers are not cancelled by ctx, Consume waits for them instead.
+				b.handle(context.WithoutCancel(ctx), q, d, handler)
+				<-unacked
+			}
+		}()
+	}
+	wg.Wait()
+
+	select {
+	case <-b.closed:
+		return errors.New("in-memory broker is closed")
+	default:
+		return ctx.Err()
+	}
+}
+
+// handle acks the delivery on success. A failed delivery is requeued after the retry delay, or
+// dead-lettered once the attempts are exhausted or the handler returned ErrDeadLetter.
+func (b *InMemoryBroker) handle(ctx context.Context, q *inMemoryQueue, d Delivery, handler RabbitMQHandler) {
+	spec := q.currentSpec()
+	b.consumeStats.delivered.Add(1)
+	ctx, span := startProcessSpan(ctx, "in-memory", spec.Name, d)
+	ctx = correlationContext(ctx, d)
+
+	err := handler(ctx, d)
+	endSpan(span, err)
+	// Like RabbitMQ, a failed delivery is acked once requeued for retry or dead-lettered.
+	b.consumeStats.acked.Add(1)
+	if err == nil {
+		return
+	}
+
+	policy := spec.Retry.withDefaults()
+	log := b.log.WithError(err).WithFields(logrus.Fields{
+		"queue":         spec.Name,
+		"routingKey":    d.RoutingKey,
+		"messageId":     d.MessageID,
+		"correlationId": d.CorrelationID,
+		"attempts":      d.DeliveryCount,
+		"maxAttempts":   policy.MaxAttempts,
+	})
+
+	headers := make(map[string]any, len(d.Headers)+2)
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+	headers[HeaderAttempts] = int32(d.DeliveryCount)
+	headers[HeaderLastError] = err.Error()
+	d.Headers = headers
+
+	if d.DeliveryCount >= policy.MaxAttempts || errors.Is(err, ErrDeadLetter) {
+		log.Error("Message handling failed, dead-lettering")
+		b.consumeStats.deadLettered.Add(1)
+		q.deadLetter(d)
+		return
+	}
+
+	log.Warn("Message handling failed, retrying later")
+	b.consumeStats.retried.Add(1)
+	d.Redelivered = true
+	d.DeliveryCount++
+	// Like the RabbitMQ retry queue, the retried message no longer expires.
+	d.Expiration = 0
+	time.AfterFunc(policy.Delay, func() {
+		q.push(d)
+	})
+}
+
+// DeadLetters returns the dead-lettered messages of queueName.
+func (b *InMemoryBroker) DeadLetters(queueName string) []Delivery {
+	b.mu.RLock()
+	q, ok := b.queues[queueName]
+	b.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]Delivery{}, q.deadLetters...)
+}
+
+// ConsumeStats returns the consume counters, see ConsumeStats. Deliveries are never nacked.
+func (b *InMemoryBroker) ConsumeStats() ConsumeStats {
+	return b.consumeStats.snapshot()
+}
+
+// Close stops the consumers, queued messages are dropped.
+func (b *InMemoryBroker) Close() error {
+	b.closeOnce.Do(func() {
+		close(b.closed)
+	})
+	return nil
+}
+
+func (q *inMemoryQueue) currentSpec() QueueSpec {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.spec
+}
+
+func (q *inMemoryQueue) setSpec(spec QueueSpec) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.spec = spec
+}
+
+func (q *inMemoryQueue) bound(routingKey RoutingKey) bool {
+	for _, binding := range q.currentSpec().Bindings {
+		if MatchRoutingKey(binding.RoutingKey, routingKey) {
+			return true
+		}
+	}
+	return false
+}
+
+func (q *inMemoryQueue) push(d Delivery) {
+	q.mu.Lock()
+	q.messages = append(q.messages, d)
+	q.mu.Unlock()
+	q.signal()
+}
+
+// pop waits for the next message, it returns false when ctx is done or the broker is closed.
+func (q *inMemoryQueue) pop(ctx context.Context, closed <-chan struct{}) (Delivery, bool) {
+	for {
+		q.mu.Lock()
+		if len(q.messages) > 0 {
+			d := q.messages[0]
+			q.messages = q.messages[1:]
+			remaining := len(q.messages)
+			q.mu.Unlock()
+
+			if d.Expiration > 0 && time.Since(d.Timestamp) > d.Expiration {
+				continue
+			}
+
+			// Wake up another worker for the remaining messages.
+			if remaining > 0 {
+				q.signal()
+			}
+			return d, true
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return Delivery{}, false
+		case <-closed:
+			return Delivery{}, false
+		case <-q.ready:
+		}
+	}
+}
+
+func (q *inMemoryQueue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+func (q *inMemoryQueue) deadLetter(d Delivery) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.deadLetters = append(q.deadLetters, d)
+}
+
+// toInMemoryDelivery applies the defaults Publish applies on RabbitMQ.
+func toInMemoryDelivery(msg Message) Delivery {
+	headers := make(map[string]any, len(msg.Headers)+1)
+	for k, v := range msg.Headers {
+		headers[k] = v
+	}
+	if msg.CausationID != "" {
+		headers[HeaderCausationID] = msg.CausationID
+	}
+
+	messageID := msg.MessageID
+	if messageID == "" {
+		messageID = uuid.NewString()
+	}
+	timestamp := msg.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	contentType := msg.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+
+	return Delivery{
+		Body:          msg.Body,
+		RoutingKey:    RoutingKey(msg.RoutingKey),
+		Exchange:      msg.Exchange,
+		ContentType:   contentType,
+		MessageID:     messageID,
+		CorrelationID: msg.CorrelationID,
+		CausationID:   msg.CausationID,
+		Headers:       headers,
+		Timestamp:     timestamp.UTC(),
+		Type:          msg.Type,
+		Priority:      msg.Priority,
+		Expiration:    msg.Expiration,
+		ReplyTo:       msg.ReplyTo,
+		DeliveryCount: 1,
+	}
+}
+
+// inMemoryReplyTo is the reply-to of in-memory requests, replies go straight to the waiting request.
+const inMemoryReplyTo = "in-memory.reply-to"
+
+// Request publishes msg and waits for the reply until ctx is done, or DefaultRequestTimeout without
+// a ctx deadline. See Requester. Like on RabbitMQ, a request no queue is bound for fails right away
+// with ErrUnroutable.
+func (b *InMemoryBroker) Request(ctx context.Context, msg Message) (Delivery, error) {
+	ctx, cancel := withRequestTimeout(ctx, DefaultRequestTimeout)
+	defer cancel()
+
+	msg = prepareRequest(ctx, msg, inMemoryReplyTo)
+	reply := b.pending.add(msg.MessageID)
+	defer b.pending.remove(msg.MessageID)
+
+	if err := b.publish(ctx, msg, true); err != nil {
+		return Delivery{}, err
+	}
+	return awaitReply(ctx, reply, b.closed)
+}
+
+// Reply hands response to the request waiting for it.
+func (b *InMemoryBroker) Reply(ctx context.Context, request Delivery, response Message) error {
+	response, err := prepareReply(request, response)
+	if err != nil {
+		return err
+	}
+	if !b.pending.resolve(toInMemoryDelivery(response)) {
+		b.log.WithField("requestId", request.MessageID).Warn("Reply arrived after its request gave up")
+	}
+	return nil
+}
+
+// QueueLag returns the number of messages of queueName waiting for a worker.
+func (b *InMemoryBroker) QueueLag(ctx context.Context, queueName string) (int, error) {
+	b.mu.RLock()
+	q, ok := b.queues[queueName]
+	b.mu.RUnlock()
+	if !ok {
+		return 0, fmt.Errorf("queue %s does not exist", queueName)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.messages), nil
+}
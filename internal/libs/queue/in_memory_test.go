@@ -0,0 +1,278 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"go-api-template/internal/libs/correlation"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testExchange = "test.events"
+
+func newTestInMemoryBroker(t *testing.T, opts InMemoryOptions, queues ...QueueSpec) *InMemoryBroker {
+	t.Helper()
+	broker := NewInMemoryBroker(opts)
+	if err := broker.EnsureTopology(testExchange, queues); err != nil {
+		t.Fatalf("EnsureTopology: %v", err)
+	}
+	t.Cleanup(func() { _ = broker.Close() })
+	return broker
+}
+
+// consume runs Consume in the background until the test ends.
+func consume(t *testing.T, broker Consumer, queueName string, handler RabbitMQHandler) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = broker.Consume(ctx, queueName, handler)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestInMemoryBrokerRoutesByPattern(t *testing.T) {
+	broker := newTestInMemoryBroker(t, InMemoryOptions{},
+		QueueSpec{Name: "users", Bindings: []Binding{{RoutingKey: "users.*"}}},
+		QueueSpec{Name: "audit", Bindings: []Binding{{RoutingKey: "#"}}},
+	)
+
+	var mu sync.Mutex
+	received := map[string][]RoutingKey{}
+	record := func(queueName string) RabbitMQHandler {
+		return func(ctx context.Context, d Delivery) error {
+			mu.Lock()
+			defer mu.Unlock()
+			received[queueName] = append(received[queueName], d.RoutingKey)
+			return nil
+		}
+	}
+	consume(t, broker, "users", record("users"))
+	consume(t, broker, "audit", record("audit"))
+
+	for _, key := range []string{"users.created", "orders.created"} {
+		if err := broker.Publish(context.Background(), Message{Exchange: testExchange, RoutingKey: key, Body: []byte(`{}`)}); err != nil {
+			t.Fatalf("Publish %s: %v", key, err)
+		}
+	}
+
+	waitFor(t, "deliveries", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received["users"]) == 1 && len(received["audit"]) == 2
+	})
+	mu.Lock()
+	defer mu.Unlock()
+	if received["users"][0] != "users.created" {
+		t.Errorf("users queue got %q, want users.created", received["users"][0])
+	}
+}
+
+func TestInMemoryBrokerRetriesThenDeadLetters(t *testing.T) {
+	broker := newTestInMemoryBroker(t, InMemoryOptions{}, QueueSpec{
+		Name:     "users",
+		Bindings: []Binding{{RoutingKey: "users.created"}},
+		Retry:    RetryPolicy{MaxAttempts: 3, Delay: time.Millisecond},
+	})
+
+	var attempts atomic.Int32
+	consume(t, broker, "users", func(ctx context.Context, d Delivery) error {
+		attempts.Add(1)
+		return errors.New("boom")
+	})
+
+	if err := broker.Publish(context.Background(), Message{Exchange: testExchange, RoutingKey: "users.created"}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	waitFor(t, "dead letter", func() bool { return len(broker.DeadLetters("users")) == 1 })
+	if got := attempts.Load(); got != 3 {
+		t.Errorf("handler ran %d times, want 3", got)
+	}
+	deadLetter := broker.DeadLetters("users")[0]
+	if deadLetter.Headers[HeaderLastError] != "boom" {
+		t.Errorf("last error = %v, want boom", deadLetter.Headers[HeaderLastError])
+	}
+	if deadLetter.DeliveryCount != 3 {
+		t.Errorf("delivery count = %d, want 3", deadLetter.DeliveryCount)
+	}
+
+	want := ConsumeStats{Delivered: 3, Acked: 3, Retried: 2, DeadLettered: 1}
+	if stats := broker.ConsumeStats(); stats != want {
+		t.Errorf("consume stats = %+v, want %+v", stats, want)
+	}
+}
+
+func TestInMemoryBrokerDeadLettersRightAway(t *testing.T) {
+	broker := newTestInMemoryBroker(t, InMemoryOptions{}, QueueSpec{
+		Name:     "users",
+		Bindings: []Binding{{RoutingKey: "users.created"}},
+		Retry:    RetryPolicy{MaxAttempts: 5, Delay: time.Millisecond},
+	})
+
+	var attempts atomic.Int32
+	consume(t, broker, "users", func(ctx context.Context, d Delivery) error {
+		attempts.Add(1)
+		return ErrDeadLetter
+	})
+
+	if err := broker.Publish(context.Background(), Message{Exchange: testExchange, RoutingKey: "users.created"}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	waitFor(t, "dead letter", func() bool { return len(broker.DeadLetters("users")) == 1 })
+	if got := attempts.Load(); got != 1 {
+		t.Errorf("handler ran %d times, want 1", got)
+	}
+}
+
+func TestInMemoryBrokerPrefetchLimitsUnackedDeliveries(t *testing.T) {
+	const prefetch = 2
+	broker := newTestInMemoryBroker(t, InMemoryOptions{Prefetch: prefetch}, QueueSpec{
+		Name:        "users",
+		Bindings:    []Binding{{RoutingKey: "users.created"}},
+		Concurrency: 5,
+	})
+
+	var inFlight, maxInFlight, handled atomic.Int32
+	release := make(chan struct{})
+	consume(t, broker, "users", func(ctx context.Context, d Delivery) error {
+		n := inFlight.Add(1)
+		for {
+			current := maxInFlight.Load()
+			if n <= current || maxInFlight.CompareAndSwap(current, n) {
+				break
+			}
+		}
+		<-release
+		inFlight.Add(-1)
+		handled.Add(1)
+		return nil
+	})
+
+	for range 6 {
+		if err := broker.Publish(context.Background(), Message{Exchange: testExchange, RoutingKey: "users.created"}); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+	}
+
+	waitFor(t, "prefetch to fill", func() bool { return inFlight.Load() == prefetch })
+	// Give idle workers a chance to take more than allowed.
+	time.Sleep(20 * time.Millisecond)
+	if lag, _ := broker.QueueLag(context.Background(), "users"); lag != 4 {
+		t.Errorf("lag = %d, want 4", lag)
+	}
+
+	close(release)
+	waitFor(t, "every delivery", func() bool { return handled.Load() == 6 })
+	if got := maxInFlight.Load(); got != prefetch {
+		t.Errorf("max unacked deliveries = %d, want %d", got, prefetch)
+	}
+}
+
+func TestInMemoryBrokerMandatoryPublish(t *testing.T) {
+	broker := newTestInMemoryBroker(t, InMemoryOptions{Mandatory: true}, QueueSpec{
+		Name:     "users",
+		Bindings: []Binding{{RoutingKey: "users.*"}},
+	})
+
+	err := broker.Publish(context.Background(), Message{Exchange: testExchange, RoutingKey: "orders.created"})
+	if !errors.Is(err, ErrUnroutable) {
+		t.Errorf("Publish error = %v, want ErrUnroutable", err)
+	}
+	if err := broker.Publish(context.Background(), Message{Exchange: testExchange, RoutingKey: "users.created"}); err != nil {
+		t.Errorf("Publish: %v", err)
+	}
+}
+
+// EnsureTopology can run again while consumers handle messages, run with -race.
+func TestInMemoryBrokerEnsureTopologyWhileConsuming(t *testing.T) {
+	spec := QueueSpec{
+		Name:     "users",
+		Bindings: []Binding{{RoutingKey: "users.created"}},
+		Retry:    RetryPolicy{MaxAttempts: 2, Delay: time.Millisecond},
+	}
+	broker := newTestInMemoryBroker(t, InMemoryOptions{}, spec)
+
+	var handled atomic.Int32
+	consume(t, broker, "users", func(ctx context.Context, d Delivery) error {
+		handled.Add(1)
+		return errors.New("retry me")
+	})
+
+	for range 20 {
+		if err := broker.Publish(context.Background(), Message{Exchange: testExchange, RoutingKey: "users.created"}); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+	}
+
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			_ = broker.EnsureTopology(testExchange, []QueueSpec{spec})
+			time.Sleep(100 * time.Microsecond)
+		}
+	}()
+	defer func() {
+		close(stop)
+		<-stopped
+	}()
+
+	waitFor(t, "dead letters", func() bool { return len(broker.DeadLetters("users")) == 20 })
+	if got := handled.Load(); got != 40 {
+		t.Errorf("handler ran %d times, want 40", got)
+	}
+}
+
+// A message published while serving a request carries its ID as the correlation ID, and the
+// consumer gets it back in its context with the message ID as the causation ID.
+func TestInMemoryBrokerPropagatesCorrelation(t *testing.T) {
+	broker := newTestInMemoryBroker(t, InMemoryOptions{},
+		QueueSpec{Name: "users", Bindings: []Binding{{RoutingKey: "users.*"}}},
+	)
+
+	type received struct{ correlationID, causationID string }
+	got := make(chan received, 1)
+	consume(t, broker, "users", func(ctx context.Context, d Delivery) error {
+		got <- received{correlation.CorrelationID(ctx), correlation.CausationID(ctx)}
+		return nil
+	})
+
+	ctx := correlation.WithCorrelationID(context.Background(), "request-1")
+	if err := broker.Publish(ctx, Message{Exchange: testExchange, RoutingKey: "users.created", MessageID: "message-1", Body: []byte(`{}`)}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	select {
+	case r := <-got:
+		if r.correlationID != "request-1" || r.causationID != "message-1" {
+			t.Errorf("handler context has correlation %q and causation %q, want request-1 and message-1", r.correlationID, r.causationID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the delivery")
+	}
+}
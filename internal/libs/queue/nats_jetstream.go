@@ -0,0 +1,676 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/sirupsen/logrus"
+)
+
+// NATS headers carrying the message properties that have no NATS equivalent.
+// The message ID is the Nats-Msg-Id header, JetStream also uses it to drop duplicate publishes.
+const (
+	natsHeaderContentType   = "Content-Type"
+	natsHeaderCorrelationID = "x-correlation-id"
+	natsHeaderMessageType   = "x-message-type"
+	natsHeaderReplyTo       = "x-reply-to"
+	natsHeaderPriority      = "x-priority"
+	natsHeaderExpiration    = "x-expiration"
+	natsHeaderTimestamp     = "x-timestamp"
+)
+
+// NATSJetStream is the NATS JetStream Broker. An exchange is a stream capturing the subjects
+// `<exchange>.>`, the routing key is the rest of the subject, and a queue is a durable pull consumer
+// filtered on its bindings (`*` is the same in NATS, a trailing `#` becomes `>`). Failed messages are
+// redelivered after the retry delay and then published to the `<stream>_dlq` stream.
+type NATSJetStream struct {
+	url string
+
+	nc *nats.Conn
+	js jetstream.JetStream
+
+	topologyMu       sync.Mutex
+	topologyExchange string
+	topologyQueues   []QueueSpec
+
+	prefetch        int
+	ackWait         time.Duration
+	publishTimeout  time.Duration
+	shutdownTimeout time.Duration
+
+	consumeStats consumeCounters
+
+	log *logrus.Entry
+}
+
+type NATSJetStreamOptions struct {
+	// Prefetch caps the unacked messages of a consumer (MaxAckPending, default 20).
+	Prefetch int
+	// AckWait is how long a message can be handled before it is redelivered (default 1m).
+	AckWait time.Duration
+	// PublishTimeout bounds the wait for the stream ack when the publish ctx has no deadline (default 5s).
+	PublishTimeout time.Duration
+	// ShutdownTimeout is how long Consume waits for in-flight handlers when ctx is done (default 30s).
+	ShutdownTimeout time.Duration
+}
+
+func NewNATSJetStream(url string, opts NATSJetStreamOptions) *NATSJetStream {
+	prefetch := opts.Prefetch
+	if prefetch <= 0 {
+		prefetch = 20
+	}
+	ackWait := opts.AckWait
+	if ackWait <= 0 {
+		ackWait = time.Minute
+	}
+	publishTimeout := opts.PublishTimeout
+	if publishTimeout <= 0 {
+		publishTimeout = 5 * time.Second
+	}
+	shutdownTimeout := opts.ShutdownTimeout
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = 30 * time.Second
+	}
+	return &NATSJetStream{
+		url:             url,
+		prefetch:        prefetch,
+		ackWait:         ackWait,
+		publishTimeout:  publishTimeout,
+		shutdownTimeout: shutdownTimeout,
+		log:             logrus.WithField("component", "nats"),
+	}
+}
+
+// Connect opens the connection, the client reconnects on its own afterwards.
+func (n *NATSJetStream) Connect() error {
+	if n.url == "" {
+		return errors.New("nats url is empty")
+	}
+
+	nc, err := nats.Connect(n.url,
+		nats.Name("go-api-template"),
+		nats.MaxReconnects(-1),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			n.log.WithError(err).Warn("NATS disconnected, reconnecting")
+		}),
+		nats.ReconnectHandler(func(_ *nats.Conn) {
+			n.log.Info("NATS reconnected")
+		}),
+	)
+	if err != nil {
+		return err
+	}
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		nc.Close()
+		return err
+	}
+
+	n.nc = nc
+	n.js = js
+	n.log.WithField("prefetch", n.prefetch).Info("NATS connected")
+	return nil
+}
+
+// IsConnected reports if the connection is currently open.
+func (n *NATSJetStream) IsConnected() bool {
+	return n.nc != nil && n.nc.IsConnected()
+}
+
+func (n *NATSJetStream) Close() error {
+	if n.nc != nil {
+		n.nc.Close()
+	}
+	return nil
+}
+
+// EnsureTopology creates or updates the stream of the exchange, its dead-letter stream and a
+// durable consumer per queue. It is safe to call on every startup.
+func (n *NATSJetStream) EnsureTopology(exchange string, queues []QueueSpec) error {
+	if n.js == nil {
+		return ErrNotConnected
+	}
+	if exchange == "" {
+		return errors.New("exchange is empty")
+	}
+
+	n.topologyMu.Lock()
+	n.topologyExchange = exchange
+	n.topologyQueues = queues
+	n.topologyMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Interest retention keeps a message until every consumer it matched acked it, like a queue per binding.
+	_, err := n.js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:      natsStreamName(exchange),
+		Subjects:  []string{exchange + ".>"},
+		Retention: jetstream.InterestPolicy,
+		Storage:   jetstream.FileStorage,
+	})
+	if err != nil {
+		return fmt.Errorf("create stream %s: %w", natsStreamName(exchange), err)
+	}
+
+	_, err = n.js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     natsDeadLetterStreamName(exchange),
+		Subjects: []string{"dlq." + exchange + ".>"},
+		Storage:  jetstream.FileStorage,
+	})
+	if err != nil {
+		return fmt.Errorf("create stream %s: %w", natsDeadLetterStreamName(exchange), err)
+	}
+
+	for _, q := range queues {
+		if q.Name == "" {
+			continue
+		}
+
+		subjects, err := natsFilterSubjects(exchange, q.Bindings)
+		if err != nil {
+			return fmt.Errorf("queue %s: %w", q.Name, err)
+		}
+
+		_, err = n.js.CreateOrUpdateConsumer(ctx, natsStreamName(exchange), jetstream.ConsumerConfig{
+			Durable:        natsConsumerName(q.Name),
+			FilterSubjects: subjects,
+			AckPolicy:      jetstream.AckExplicitPolicy,
+			AckWait:        n.ackWait,
+			MaxAckPending:  n.prefetch,
+		})
+		if err != nil {
+			return fmt.Errorf("create consumer %s: %w", natsConsumerName(q.Name), err)
+		}
+	}
+	return nil
+}
+
+// Publish stores msg in the stream of its exchange and waits for the stream ack.
+// An exchange without stream fails with ErrUnroutable. The stream captures every routing key of the
+// exchange, a message no consumer filter matches is acked and dropped.
+func (n *NATSJetStream) Publish(ctx context.Context, msg Message) error {
+	if msg.Exchange == "" {
+		return errors.New("publish exchange is empty")
+	}
+	if msg.RoutingKey == "" {
+		return errors.New("publish routing key is empty")
+	}
+	if n.js == nil {
+		return ErrNotConnected
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, n.publishTimeout)
+		defer cancel()
+	}
+
+	ctx, span, msg := startPublishSpan(ctx, "nats", withCorrelation(ctx, msg))
+	natsMsg, messageID := toNATSMsg(msg.Exchange+"."+msg.RoutingKey, msg)
+	_, err := n.js.PublishMsg(ctx, natsMsg, jetstream.WithMsgID(messageID))
+	if errors.Is(err, jetstream.ErrNoStreamResponse) || errors.Is(err, nats.ErrNoResponders) {
+		err = fmt.Errorf("%w: %s/%s", ErrUnroutable, msg.Exchange, msg.RoutingKey)
+	}
+	endSpan(span, err)
+	return err
+}
+
+// Consume handles the messages of queueName until ctx is done, then waits for the in-flight handlers.
+// The queue must have been declared with EnsureTopology. It fails when the messages stop before ctx
+// is done, ex: the consumer was deleted or the connection closed.
+func (n *NATSJetStream) Consume(ctx context.Context, queueName string, handler RabbitMQHandler) error {
+	if handler == nil {
+		return errors.New("handler is nil")
+	}
+	if n.js == nil {
+		return ErrNotConnected
+	}
+
+	spec := n.queueSpec(queueName)
+	exchange := n.exchange()
+
+	consumer, err := n.js.Consumer(ctx, natsStreamName(exchange), natsConsumerName(queueName))
+	if err != nil {
+		return fmt.Errorf("consumer %s: %w", natsConsumerName(queueName), err)
+	}
+
+	messages, err := consumer.Messages(jetstream.PullMaxMessages(n.prefetch))
+	if err != nil {
+		return err
+	}
+
+	concurrency := max(spec.Concurrency, 1)
+	log := n.log.WithFields(logrus.Fields{
+		"queue":       queueName,
+		"concurrency": concurrency,
+		"partitioned": spec.PartitionKey != nil,
+	})
+	log.Info("NATS consuming")
+
+	var partitionKey func(msg jetstream.Msg) string
+	if spec.PartitionKey != nil {
+		partitionKey = func(msg jetstream.Msg) string { return spec.PartitionKey(toNATSDelivery(exchange, msg)) }
+	}
+
+	pool := newWorkerPool(concurrency, partitionKey, func(msg jetstream.Msg) {
+		// In-flight handlers are not cancelled by ctx, shutdown waits for them instead.
+		n.handleMsg(context.WithoutCancel(ctx), exchange, spec, msg, handler)
+	})
+
+	// Next blocks until a message arrives, stopping the iterator unblocks it.
+	stopped := make(chan struct{})
+	defer close(stopped)
+	go func() {
+		select {
+		case <-ctx.Done():
+			messages.Stop()
+		case <-stopped:
+		}
+	}()
+
+	for {
+		msg, err := messages.Next()
+		if errors.Is(err, jetstream.ErrMsgIteratorClosed) {
+			break
+		}
+		if err != nil {
+			// The iterator keeps going after transient errors, ex: missed heartbeats while reconnecting.
+			log.WithError(err).Warn("NATS consumer error")
+			continue
+		}
+
+		if !pool.dispatch(ctx, msg) {
+			// ctx was cancelled while every worker was busy.
+			n.consumeStats.nacked.Add(1)
+			_ = msg.Nak()
+			break
+		}
+	}
+
+	messages.Stop()
+	if !pool.stop(n.shutdownTimeout) {
+		log.WithField("timeout", n.shutdownTimeout).Warn("NATS consumer stopped before in-flight handlers finished")
+	}
+	if ctx.Err() == nil {
+		// The iterator ended on its own, ex: the connection was closed.
+		return fmt.Errorf("consumer %s stopped: %w", natsConsumerName(queueName), jetstream.ErrMsgIteratorClosed)
+	}
+	return ctx.Err()
+}
+
+// handleMsg acks the message on success. A failed message is redelivered after the retry delay,
+// or published to the dead-letter stream once the attempts are exhausted.
+func (n *NATSJetStream) handleMsg(ctx context.Context, exchange string, spec QueueSpec, msg jetstream.Msg, handler RabbitMQHandler) {
+	delivery := toNATSDelivery(exchange, msg)
+	n.consumeStats.delivered.Add(1)
+
+	if delivery.Expiration > 0 && time.Since(delivery.Timestamp) > delivery.Expiration {
+		n.consumeStats.acked.Add(1)
+		_ = msg.Ack()
+		return
+	}
+
+	ctx, span := startProcessSpan(ctx, "nats", spec.Name, delivery)
+	err := handler(correlationContext(ctx, delivery), delivery)
+	endSpan(span, err)
+	if err == nil {
+		n.consumeStats.acked.Add(1)
+		_ = msg.Ack()
+		return
+	}
+
+	policy := spec.Retry.withDefaults()
+	log := n.log.WithError(err).WithFields(logrus.Fields{
+		"queue":         spec.Name,
+		"routingKey":    delivery.RoutingKey,
+		"messageId":     delivery.MessageID,
+		"correlationId": delivery.CorrelationID,
+		"attempts":      delivery.DeliveryCount,
+		"maxAttempts":   policy.MaxAttempts,
+	})
+
+	if delivery.DeliveryCount < policy.MaxAttempts && !errors.Is(err, ErrDeadLetter) {
+		log.Warn("Message handling failed, retrying later")
+		n.consumeStats.retried.Add(1)
+		_ = msg.NakWithDelay(policy.Delay)
+		return
+	}
+
+	log.Error("Message handling failed, dead-lettering")
+	deadLetter := nats.NewMsg("dlq." + exchange + "." + spec.Name)
+	deadLetter.Data = msg.Data()
+	for k, v := range msg.Headers() {
+		deadLetter.Header[k] = v
+	}
+	deadLetter.Header.Set(HeaderAttempts, strconv.Itoa(delivery.DeliveryCount))
+	deadLetter.Header.Set(HeaderLastError, err.Error())
+	deadLetter.Header.Set(HeaderOriginalExchange, exchange)
+	deadLetter.Header.Set(HeaderOriginalRoutingKey, string(delivery.RoutingKey))
+	deadLetter.Header.Set(HeaderDeadLetteredAt, time.Now().UTC().Format(time.RFC3339))
+	deadLetter.Header.Set(HeaderDeadLetteredFromQueue, spec.Name)
+	// The dead letter is a new message, a duplicate ID would be dropped by the stream.
+	deadLetter.Header.Del(nats.MsgIdHdr)
+
+	publishCtx, cancel := context.WithTimeout(ctx, n.publishTimeout)
+	defer cancel()
+	if _, err := n.js.PublishMsg(publishCtx, deadLetter); err != nil {
+		log.WithField("republishError", err).Error("Can't dead-letter failed message, redelivering")
+		n.consumeStats.nacked.Add(1)
+		_ = msg.NakWithDelay(policy.Delay)
+		return
+	}
+	n.consumeStats.deadLettered.Add(1)
+	n.consumeStats.acked.Add(1)
+	_ = msg.Ack()
+}
+
+// ConsumeStats returns the consume counters, see ConsumeStats. A retry is a delayed redelivery of the
+// same message, it is counted as retried and not as acked or nacked.
+func (n *NATSJetStream) ConsumeStats() ConsumeStats {
+	return n.consumeStats.snapshot()
+}
+
+// Request publishes msg to the stream with a fresh inbox as reply-to and waits for the reply on it
+// until ctx is done, or DefaultRequestTimeout without a ctx deadline. See Requester.
+func (n *NATSJetStream) Request(ctx context.Context, msg Message) (Delivery, error) {
+	if n.nc == nil {
+		return Delivery{}, ErrNotConnected
+	}
+
+	ctx, cancel := withRequestTimeout(ctx, DefaultRequestTimeout)
+	defer cancel()
+
+	inbox := n.nc.NewInbox()
+	sub, err := n.nc.SubscribeSync(inbox)
+	if err != nil {
+		return Delivery{}, err
+	}
+	defer func() { _ = sub.Unsubscribe() }()
+
+	// Unlike a dropped event, an unroutable request would wait for its timeout.
+	if err := n.checkRoutable(ctx, msg.Exchange, msg.RoutingKey); err != nil {
+		return Delivery{}, err
+	}
+
+	msg = prepareRequest(ctx, msg, inbox)
+	if err := n.Publish(ctx, msg); err != nil {
+		return Delivery{}, err
+	}
+
+	natsReply, err := sub.NextMsgWithContext(ctx)
+	if err != nil {
+		return Delivery{}, err
+	}
+
+	reply := natsHeaderDelivery(natsReply.Header, natsReply.Data)
+	if err := replyError(reply); err != nil {
+		return reply, err
+	}
+	return reply, nil
+}
+
+// checkRoutable fails with ErrUnroutable when no consumer of the exchange stream filters the routing key.
+// The consumers are listed from the server, the queue may be declared by another service.
+func (n *NATSJetStream) checkRoutable(ctx context.Context, exchange string, routingKey string) error {
+	stream, err := n.js.Stream(ctx, natsStreamName(exchange))
+	if errors.Is(err, jetstream.ErrStreamNotFound) {
+		return fmt.Errorf("%w: %s/%s", ErrUnroutable, exchange, routingKey)
+	}
+	if err != nil {
+		return err
+	}
+
+	subject := exchange + "." + routingKey
+	routed := false
+	consumers := stream.ListConsumers(ctx)
+	// The lister blocks until its channel is drained.
+	for info := range consumers.Info() {
+		filters := info.Config.FilterSubjects
+		if info.Config.FilterSubject != "" {
+			filters = append(filters, info.Config.FilterSubject)
+		}
+		if len(filters) == 0 {
+			routed = true
+		}
+		for _, filter := range filters {
+			if natsSubjectCovers(filter, subject) {
+				routed = true
+			}
+		}
+	}
+	if err := consumers.Err(); err != nil {
+		return err
+	}
+	if !routed {
+		return fmt.Errorf("%w: %s/%s", ErrUnroutable, exchange, routingKey)
+	}
+	return nil
+}
+
+// Reply publishes response to the inbox of the request, outside of the streams.
+func (n *NATSJetStream) Reply(ctx context.Context, request Delivery, response Message) error {
+	if n.nc == nil {
+		return ErrNotConnected
+	}
+	response, err := prepareReply(request, response)
+	if err != nil {
+		return err
+	}
+
+	natsMsg, _ := toNATSMsg(response.RoutingKey, response)
+	return n.nc.PublishMsg(natsMsg)
+}
+
+func (n *NATSJetStream) queueSpec(queueName string) QueueSpec {
+	n.topologyMu.Lock()
+	defer n.topologyMu.Unlock()
+
+	for _, q := range n.topologyQueues {
+		if q.Name == queueName {
+			return q
+		}
+	}
+	return QueueSpec{Name: queueName}
+}
+
+func (n *NATSJetStream) exchange() string {
+	n.topologyMu.Lock()
+	defer n.topologyMu.Unlock()
+	return n.topologyExchange
+}
+
+func toNATSMsg(subject string, msg Message) (*nats.Msg, string) {
+	publishing := toPublishing(msg)
+
+	natsMsg := nats.NewMsg(subject)
+	natsMsg.Header.Set(nats.MsgIdHdr, publishing.MessageId)
+	natsMsg.Data = publishing.Body
+	for k, v := range publishing.Headers {
+		natsMsg.Header.Set(k, fmt.Sprint(v))
+	}
+
+	setHeader := func(key string, value string) {
+		if value != "" {
+			natsMsg.Header.Set(key, value)
+		}
+	}
+	setHeader(natsHeaderContentType, publishing.ContentType)
+	setHeader(natsHeaderCorrelationID, publishing.CorrelationId)
+	setHeader(natsHeaderMessageType, publishing.Type)
+	setHeader(natsHeaderReplyTo, publishing.ReplyTo)
+	setHeader(natsHeaderExpiration, publishing.Expiration)
+	setHeader(natsHeaderTimestamp, publishing.Timestamp.Format(time.RFC3339Nano))
+	if publishing.Priority > 0 {
+		setHeader(natsHeaderPriority, strconv.Itoa(int(publishing.Priority)))
+	}
+
+	return natsMsg, publishing.MessageId
+}
+
+func toNATSDelivery(exchange string, msg jetstream.Msg) Delivery {
+	deliveryCount := 1
+	if metadata, err := msg.Metadata(); err == nil {
+		deliveryCount = int(metadata.NumDelivered)
+	}
+
+	d := natsHeaderDelivery(msg.Headers(), msg.Data())
+	d.RoutingKey = RoutingKey(strings.TrimPrefix(msg.Subject(), exchange+"."))
+	d.Exchange = exchange
+	d.Redelivered = deliveryCount > 1
+	d.DeliveryCount = deliveryCount
+	return d
+}
+
+// natsHeaderDelivery reads the message properties from the headers.
+func natsHeaderDelivery(header nats.Header, data []byte) Delivery {
+	headers := make(map[string]any, len(header))
+	for k := range header {
+		headers[k] = header.Get(k)
+	}
+
+	var expiration time.Duration
+	if ms, err := strconv.ParseInt(header.Get(natsHeaderExpiration), 10, 64); err == nil {
+		expiration = time.Duration(ms) * time.Millisecond
+	}
+	timestamp, _ := time.Parse(time.RFC3339Nano, header.Get(natsHeaderTimestamp))
+	priority, _ := strconv.ParseUint(header.Get(natsHeaderPriority), 10, 8)
+
+	return Delivery{
+		Body:          data,
+		ContentType:   header.Get(natsHeaderContentType),
+		MessageID:     header.Get(nats.MsgIdHdr),
+		CorrelationID: header.Get(natsHeaderCorrelationID),
+		CausationID:   header.Get(HeaderCausationID),
+		Headers:       headers,
+		Timestamp:     timestamp,
+		Type:          header.Get(natsHeaderMessageType),
+		Priority:      uint8(priority),
+		Expiration:    expiration,
+		ReplyTo:       header.Get(natsHeaderReplyTo),
+		DeliveryCount: 1,
+	}
+}
+
+// natsFilterSubjects maps the bindings to consumer filter subjects. NATS has no `#` matching zero
+// words, so `orders.#` becomes `orders` and `orders.>`, and `#` is only supported as the last word.
+// Subjects covered by another one are left out, JetStream rejects overlapping filters:
+// `users.*` and `users.#` become `users` and `users.>`.
+func natsFilterSubjects(exchange string, bindings []Binding) ([]string, error) {
+	var candidates []string
+	seen := map[string]bool{}
+	add := func(subject string) {
+		if !seen[subject] {
+			seen[subject] = true
+			candidates = append(candidates, subject)
+		}
+	}
+
+	for _, b := range bindings {
+		if b.RoutingKey == "" {
+			continue
+		}
+
+		words := strings.Split(string(b.RoutingKey), ".")
+		for i, word := range words {
+			if word == "#" && i != len(words)-1 {
+				return nil, fmt.Errorf("binding %s: # is only supported as the last word on NATS", b.RoutingKey)
+			}
+		}
+
+		if words[len(words)-1] != "#" {
+			add(exchange + "." + string(b.RoutingKey))
+			continue
+		}
+
+		prefix := strings.Join(append([]string{exchange}, words[:len(words)-1]...), ".")
+		if len(words) > 1 {
+			add(prefix)
+		}
+		add(prefix + ".>")
+	}
+
+	var subjects []string
+	for _, subject := range candidates {
+		covered := slices.ContainsFunc(candidates, func(other string) bool {
+			return other != subject && natsSubjectCovers(other, subject)
+		})
+		if !covered {
+			subjects = append(subjects, subject)
+		}
+	}
+
+	if len(subjects) == 0 {
+		return nil, errors.New("queue has no bindings")
+	}
+	return subjects, nil
+}
+
+// natsSubjectCovers reports if every subject matching filter also matches cover.
+func natsSubjectCovers(cover string, filter string) bool {
+	coverTokens := strings.Split(cover, ".")
+	filterTokens := strings.Split(filter, ".")
+
+	for i, token := range coverTokens {
+		if token == ">" {
+			return len(filterTokens) > i
+		}
+		if i >= len(filterTokens) {
+			return false
+		}
+		switch filterTokens[i] {
+		case ">":
+			return false
+		case "*":
+			if token != "*" {
+				return false
+			}
+		default:
+			if token != "*" && token != filterTokens[i] {
+				return false
+			}
+		}
+	}
+	return len(coverTokens) == len(filterTokens)
+}
+
+// Stream and consumer names can't contain dots, wildcards or spaces.
+var natsNameReplacer = strings.NewReplacer(".", "_", "*", "_", ">", "_", " ", "_", "/", "_", "\\", "_")
+
+func natsStreamName(exchange string) string {
+	return natsNameReplacer.Replace(exchange)
+}
+
+func natsDeadLetterStreamName(exchange string) string {
+	return natsStreamName(exchange) + "_dlq"
+}
+
+func natsConsumerName(queueName string) string {
+	return natsNameReplacer.Replace(queueName)
+}
+
+// QueueLag returns the number of messages of the stream matching the consumer of queueName not yet delivered.
+func (n *NATSJetStream) QueueLag(ctx context.Context, queueName string) (int, error) {
+	if n.js == nil {
+		return 0, ErrNotConnected
+	}
+
+	consumer, err := n.js.Consumer(ctx, natsStreamName(n.exchange()), natsConsumerName(queueName))
+	if err != nil {
+		return 0, err
+	}
+	info, err := consumer.Info(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return int(info.NumPending), nil
+}
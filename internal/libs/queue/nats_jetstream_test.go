@@ -0,0 +1,285 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+)
+
+func TestNATSFilterSubjects(t *testing.T) {
+	tests := []struct {
+		name     string
+		bindings []RoutingKey
+		want     []string
+	}{
+		{name: "exact key", bindings: []RoutingKey{"users.created"}, want: []string{"ex.users.created"}},
+		{name: "trailing #", bindings: []RoutingKey{"users.#"}, want: []string{"ex.users", "ex.users.>"}},
+		{name: "only #", bindings: []RoutingKey{"#"}, want: []string{"ex.>"}},
+		{name: "key covered by *", bindings: []RoutingKey{"users.created", "users.*"}, want: []string{"ex.users.*"}},
+		{name: "* covered by #", bindings: []RoutingKey{"users.*", "users.#"}, want: []string{"ex.users", "ex.users.>"}},
+		{name: "everything covered by #", bindings: []RoutingKey{"users.*", "orders.created", "#"}, want: []string{"ex.>"}},
+		{name: "disjoint wildcards", bindings: []RoutingKey{"users.*", "*.created"}, want: []string{"ex.users.*", "ex.*.created"}},
+		{name: "duplicates", bindings: []RoutingKey{"users.created", "users.created"}, want: []string{"ex.users.created"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bindings []Binding
+			for _, key := range tt.bindings {
+				bindings = append(bindings, Binding{RoutingKey: key})
+			}
+			got, err := natsFilterSubjects("ex", bindings)
+			if err != nil {
+				t.Fatalf("natsFilterSubjects: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("subjects = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNATSFilterSubjectsRejectsInnerHash(t *testing.T) {
+	_, err := natsFilterSubjects("ex", []Binding{{RoutingKey: "users.#.created"}})
+	if err == nil {
+		t.Error("expected an error for # before the last word")
+	}
+}
+
+// newTestNATSJetStream connects to an embedded JetStream server with the given topology.
+func newTestNATSJetStream(t *testing.T, queues ...QueueSpec) *NATSJetStream {
+	t.Helper()
+
+	ns, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      server.RANDOM_PORT,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	if err != nil {
+		t.Fatalf("nats server: %v", err)
+	}
+	ns.Start()
+	t.Cleanup(ns.Shutdown)
+	if !ns.ReadyForConnections(5 * time.Second) {
+		t.Fatal("nats server is not ready")
+	}
+
+	broker := NewNATSJetStream(ns.ClientURL(), NATSJetStreamOptions{AckWait: 5 * time.Second})
+	if err := broker.Connect(); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	t.Cleanup(func() { _ = broker.Close() })
+
+	if err := broker.EnsureTopology(testExchange, queues); err != nil {
+		t.Fatalf("EnsureTopology: %v", err)
+	}
+	return broker
+}
+
+func TestNATSJetStreamPublishConsume(t *testing.T) {
+	broker := newTestNATSJetStream(t,
+		QueueSpec{Name: "users", Bindings: []Binding{{RoutingKey: "users.*"}, {RoutingKey: "users.#"}}},
+		QueueSpec{Name: "orders", Bindings: []Binding{{RoutingKey: "orders.created"}}},
+	)
+
+	var mu sync.Mutex
+	var received []Delivery
+	consume(t, broker, "users", func(ctx context.Context, d Delivery) error {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, d)
+		return nil
+	})
+
+	msg := Message{
+		Exchange:      testExchange,
+		RoutingKey:    "users.created",
+		Body:          []byte(`{"id":"1"}`),
+		ContentType:   "application/json",
+		MessageID:     "message-1",
+		CorrelationID: "correlation-1",
+		Type:          "users.created",
+	}
+	if err := broker.Publish(context.Background(), msg); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if err := broker.Publish(context.Background(), Message{Exchange: testExchange, RoutingKey: "orders.created"}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	waitFor(t, "delivery", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == 1
+	})
+
+	mu.Lock()
+	d := received[0]
+	mu.Unlock()
+	if d.RoutingKey != "users.created" || d.Exchange != testExchange {
+		t.Errorf("routed to %s/%s, want %s/users.created", d.Exchange, d.RoutingKey, testExchange)
+	}
+	if string(d.Body) != `{"id":"1"}` || d.MessageID != "message-1" || d.CorrelationID != "correlation-1" || d.Type != "users.created" {
+		t.Errorf("unexpected delivery %+v", d)
+	}
+	if d.DeliveryCount != 1 {
+		t.Errorf("delivery count = %d, want 1", d.DeliveryCount)
+	}
+}
+
+func TestNATSJetStreamUnroutablePublish(t *testing.T) {
+	broker := newTestNATSJetStream(t, QueueSpec{Name: "users", Bindings: []Binding{{RoutingKey: "users.*"}}})
+
+	err := broker.Publish(context.Background(), Message{Exchange: "other.events", RoutingKey: "users.created"})
+	if !errors.Is(err, ErrUnroutable) {
+		t.Errorf("Publish error = %v, want ErrUnroutable", err)
+	}
+}
+
+// A request no consumer filters fails right away instead of waiting for its timeout.
+func TestNATSJetStreamUnroutableRequest(t *testing.T) {
+	broker := newTestNATSJetStream(t, QueueSpec{Name: "users.rpc", Bindings: []Binding{{RoutingKey: "users.get"}}})
+
+	start := time.Now()
+	_, err := broker.Request(context.Background(), Message{Exchange: testExchange, RoutingKey: "orders.get"})
+	if !errors.Is(err, ErrUnroutable) {
+		t.Errorf("Request error = %v, want ErrUnroutable", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Request took %s to fail", elapsed)
+	}
+}
+
+func TestNATSJetStreamRequestReply(t *testing.T) {
+	broker := newTestNATSJetStream(t, QueueSpec{Name: "users.rpc", Bindings: []Binding{{RoutingKey: "users.*"}}})
+	consume(t, broker, "users.rpc", func(ctx context.Context, d Delivery) error {
+		return broker.Reply(ctx, d, Message{Body: []byte(`{"id":"1"}`)})
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	reply, err := broker.Request(ctx, Message{Exchange: testExchange, RoutingKey: "users.get", Body: []byte(`"1"`)})
+	if err != nil {
+		t.Fatalf("Request: %v", err)
+	}
+	if string(reply.Body) != `{"id":"1"}` {
+		t.Errorf("reply = %s", reply.Body)
+	}
+}
+
+// Consume must not return nil while ctx is live, the server would keep running without consumer.
+func TestNATSJetStreamConsumeFailsWhenTheConnectionCloses(t *testing.T) {
+	broker := newTestNATSJetStream(t, QueueSpec{Name: "users", Bindings: []Binding{{RoutingKey: "users.*"}}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- broker.Consume(ctx, "users", func(ctx context.Context, d Delivery) error { return nil })
+	}()
+	// Let the consumer start pulling.
+	time.Sleep(100 * time.Millisecond)
+
+	_ = broker.Close()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Consume returned nil after the connection closed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Consume kept running after the connection closed")
+	}
+}
+
+func TestNATSJetStreamRetries(t *testing.T) {
+	broker := newTestNATSJetStream(t, QueueSpec{
+		Name:     "users",
+		Bindings: []Binding{{RoutingKey: "users.created"}},
+		Retry:    RetryPolicy{MaxAttempts: 3, Delay: 10 * time.Millisecond},
+	})
+
+	var attempts atomic.Int32
+	var lastDeliveryCount atomic.Int32
+	consume(t, broker, "users", func(ctx context.Context, d Delivery) error {
+		lastDeliveryCount.Store(int32(d.DeliveryCount))
+		if attempts.Add(1) < 2 {
+			return errors.New("not yet")
+		}
+		return nil
+	})
+
+	if err := broker.Publish(context.Background(), Message{Exchange: testExchange, RoutingKey: "users.created"}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	waitFor(t, "retry", func() bool { return attempts.Load() == 2 })
+	if got := lastDeliveryCount.Load(); got != 2 {
+		t.Errorf("delivery count = %d, want 2", got)
+	}
+	waitFor(t, "ack", func() bool {
+		lag, err := broker.QueueLag(context.Background(), "users")
+		return err == nil && lag == 0
+	})
+
+	want := ConsumeStats{Delivered: 2, Acked: 1, Retried: 1}
+	if stats := broker.ConsumeStats(); stats != want {
+		t.Errorf("consume stats = %+v, want %+v", stats, want)
+	}
+}
+
+func TestNATSJetStreamDeadLetters(t *testing.T) {
+	broker := newTestNATSJetStream(t, QueueSpec{
+		Name:     "users",
+		Bindings: []Binding{{RoutingKey: "users.created"}},
+		Retry:    RetryPolicy{MaxAttempts: 2, Delay: 10 * time.Millisecond},
+	})
+
+	var attempts atomic.Int32
+	consume(t, broker, "users", func(ctx context.Context, d Delivery) error {
+		attempts.Add(1)
+		return errors.New("boom")
+	})
+
+	if err := broker.Publish(context.Background(), Message{Exchange: testExchange, RoutingKey: "users.created", Body: []byte(`{}`)}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	ctx := context.Background()
+	stream, err := broker.js.Stream(ctx, natsDeadLetterStreamName(testExchange))
+	if err != nil {
+		t.Fatalf("dead-letter stream: %v", err)
+	}
+	waitFor(t, "dead letter", func() bool {
+		info, err := stream.Info(ctx)
+		return err == nil && info.State.Msgs == 1
+	})
+	if got := attempts.Load(); got != 2 {
+		t.Errorf("handler ran %d times, want 2", got)
+	}
+	waitFor(t, "dead-letter count", func() bool { return broker.ConsumeStats().DeadLettered == 1 })
+
+	deadLetter, err := stream.GetLastMsgForSubject(ctx, "dlq."+testExchange+".users")
+	if err != nil {
+		t.Fatalf("dead letter: %v", err)
+	}
+	for header, want := range map[string]string{
+		HeaderAttempts:              "2",
+		HeaderLastError:             "boom",
+		HeaderOriginalExchange:      testExchange,
+		HeaderOriginalRoutingKey:    "users.created",
+		HeaderDeadLetteredFromQueue: "users",
+	} {
+		if got := deadLetter.Header.Get(header); got != want {
+			t.Errorf("header %s = %q, want %q", header, got, want)
+		}
+	}
+}
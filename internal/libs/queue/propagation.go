@@ -0,0 +1,54 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"go-api-template/internal/libs/correlation"
+)
+
+// correlationContext restores the correlation ID of d in ctx, with d as the cause of what the handler publishes.
+func correlationContext(ctx context.Context, d Delivery) context.Context {
+	ctx = correlation.WithCorrelationID(ctx, d.CorrelationID)
+	return correlation.WithCausationID(ctx, d.MessageID)
+}
+
+// withCorrelation fills the correlation and causation IDs of msg left empty from ctx, so messages published
+// while serving a request carry its request ID, and messages published by a handler the handled message.
+func withCorrelation(ctx context.Context, msg Message) Message {
+	if msg.CorrelationID == "" {
+		msg.CorrelationID = correlation.CorrelationID(ctx)
+	}
+	if msg.CausationID == "" {
+		msg.CausationID = correlation.CausationID(ctx)
+	}
+	return msg
+}
+
+// HeaderCarrier adapts message headers to the OpenTelemetry TextMapCarrier interface,
+// so the trace context travels with the message. Set needs a non-nil map.
+type HeaderCarrier map[string]any
+
+func (c HeaderCarrier) Get(key string) string {
+	switch v := c[key].(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func (c HeaderCarrier) Set(key string, value string) {
+	c[key] = value
+}
+
+func (c HeaderCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/rabbitmq/amqp091-go"
@@ -14,21 +15,81 @@ import (
 type RabbitMQ struct {
 	url string
 
-	connMu sync.RWMutex
-	conn   *amqp091.Connection
+	connMu    sync.RWMutex
+	conn      *amqp091.Connection
+	connected chan struct{} // closed while connected, replaced when the connection drops
+	closing   bool
+	done      chan struct{}
 
-	pubMu sync.Mutex
-	pubCh *amqp091.Channel
+	// pubMu orders the publish calls only, confirms are waited for concurrently.
+	pubMu      sync.Mutex
+	pubCh      *amqp091.Channel
+	pubReturns *publishReturns
 
 	consumeCh *amqp091.Channel
 
-	prefetch int
+	rpcMu   sync.Mutex
+	rpc     *rpcSession
+	pending pendingRequests
+
+	// Topology declared by EnsureTopology, declared again after a reconnection.
+	topologyMu       sync.Mutex
+	topologyExchange string
+	topologyQueues   []QueueSpec
+
+	prefetch          int
+	confirm           bool
+	mandatory         bool
+	publishTimeout    time.Duration
+	reconnectMinDelay time.Duration
+	reconnectMaxDelay time.Duration
+	shutdownTimeout   time.Duration
+	directReplyTo     bool
+	requestTimeout    time.Duration
+
+	stats        publishCounters
+	consumeStats consumeCounters
 
 	log *logrus.Entry
 }
 
 type RabbitMQOptions struct {
 	Prefetch int
+	// ConfirmPublish puts the publish channel in confirm mode: Publish waits for the broker ack/nack.
+	// Publishes share the channel and wait for their own confirm concurrently.
+	ConfirmPublish bool
+	// Mandatory makes Publish fail with ErrUnroutable when no queue is bound to the routing key.
+	// The broker only reports it reliably in confirm mode, so it needs ConfirmPublish.
+	Mandatory bool
+	// PublishTimeout bounds the wait for a confirm when the publish ctx has no deadline (default 5s).
+	PublishTimeout time.Duration
+	// ReconnectMinDelay and ReconnectMaxDelay bound the exponential backoff (with jitter)
+	// between reconnection attempts (default 500ms and 30s).
+	ReconnectMinDelay time.Duration
+	ReconnectMaxDelay time.Duration
+	// ShutdownTimeout is how long Consume waits for in-flight handlers when ctx is done (default 30s).
+	ShutdownTimeout time.Duration
+	// DirectReplyTo receives replies with RabbitMQ direct reply-to instead of an exclusive callback queue.
+	DirectReplyTo bool
+	// RequestTimeout bounds the wait for a reply when the request ctx has no deadline (default 30s).
+	RequestTimeout time.Duration
+}
+
+// PublishStats are the publish counters of a RabbitMQ client since it was created.
+type PublishStats struct {
+	Published  int64
+	Confirmed  int64
+	Nacked     int64
+	Unroutable int64
+	Failed     int64
+}
+
+type publishCounters struct {
+	published  atomic.Int64
+	confirmed  atomic.Int64
+	nacked     atomic.Int64
+	unroutable atomic.Int64
+	failed     atomic.Int64
 }
 
 func NewRabbitMQ(url string, opts RabbitMQOptions) *RabbitMQ {
@@ -36,72 +97,88 @@ func NewRabbitMQ(url string, opts RabbitMQOptions) *RabbitMQ {
 	if prefetch <= 0 {
 		prefetch = 20
 	}
+	publishTimeout := opts.PublishTimeout
+	if publishTimeout <= 0 {
+		publishTimeout = 5 * time.Second
+	}
+	reconnectMinDelay := opts.ReconnectMinDelay
+	if reconnectMinDelay <= 0 {
+		reconnectMinDelay = 500 * time.Millisecond
+	}
+	reconnectMaxDelay := opts.ReconnectMaxDelay
+	if reconnectMaxDelay < reconnectMinDelay {
+		reconnectMaxDelay = max(30*time.Second, reconnectMinDelay)
+	}
+	shutdownTimeout := opts.ShutdownTimeout
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = 30 * time.Second
+	}
+	requestTimeout := opts.RequestTimeout
+	if requestTimeout <= 0 {
+		requestTimeout = DefaultRequestTimeout
+	}
 	return &RabbitMQ{
-		url:      url,
-		prefetch: prefetch,
-		log:      logrus.WithField("component", "rabbitmq"),
+		url:               url,
+		connected:         make(chan struct{}),
+		done:              make(chan struct{}),
+		prefetch:          prefetch,
+		confirm:           opts.ConfirmPublish,
+		mandatory:         opts.Mandatory && opts.ConfirmPublish,
+		publishTimeout:    publishTimeout,
+		reconnectMinDelay: reconnectMinDelay,
+		reconnectMaxDelay: reconnectMaxDelay,
+		shutdownTimeout:   shutdownTimeout,
+		directReplyTo:     opts.DirectReplyTo,
+		requestTimeout:    requestTimeout,
+		log:               logrus.WithField("component", "rabbitmq"),
 	}
 }
 
+// Connect opens the connection and its channels. Once connected, the connection is watched
+// and re-established automatically (see reconnect) until Close is called.
 func (r *RabbitMQ) Connect() error {
 	if r.url == "" {
 		return errors.New("rabbitmq url is empty")
 	}
 
 	r.connMu.RLock()
-	if r.conn != nil && !r.conn.IsClosed() && r.pubCh != nil && r.consumeCh != nil {
+	if r.closing {
 		r.connMu.RUnlock()
-		return nil
+		return errors.New("rabbitmq is closed")
 	}
-	r.connMu.RUnlock()
-
-	r.connMu.Lock()
-	defer r.connMu.Unlock()
-
 	if r.conn != nil && !r.conn.IsClosed() && r.pubCh != nil && r.consumeCh != nil {
+		r.connMu.RUnlock()
 		return nil
 	}
+	r.connMu.RUnlock()
 
-	conn, err := amqp091.Dial(r.url)
-	if err != nil {
-		return err
-	}
-
-	pubCh, err := conn.Channel()
+	session, err := r.dial()
 	if err != nil {
-		_ = conn.Close()
 		return err
 	}
 
-	consumeCh, err := conn.Channel()
-	if err != nil {
-		_ = pubCh.Close()
-		_ = conn.Close()
-		return err
-	}
-
-	if err := consumeCh.Qos(r.prefetch, 0, false); err != nil {
-		_ = consumeCh.Close()
-		_ = pubCh.Close()
-		_ = conn.Close()
-		return err
-	}
-
-	r.conn = conn
-	r.pubCh = pubCh
-	r.consumeCh = consumeCh
-
-	r.log.WithFields(logrus.Fields{
-		"prefetch": r.prefetch,
-	}).Info("RabbitMQ connected")
+	r.setSession(session)
+	go r.watch(session)
 
 	return nil
 }
 
+// IsConnected reports if the connection and its channels are currently open.
+func (r *RabbitMQ) IsConnected() bool {
+	r.connMu.RLock()
+	defer r.connMu.RUnlock()
+	return r.conn != nil && !r.conn.IsClosed() && r.pubCh != nil && !r.pubCh.IsClosed() && r.consumeCh != nil && !r.consumeCh.IsClosed()
+}
+
 func (r *RabbitMQ) Close() error {
 	r.connMu.Lock()
 	defer r.connMu.Unlock()
 
+	if !r.closing {
+		r.closing = true
+		close(r.done)
+	}
+
 	var closeErr error
 	if r.consumeCh != nil {
 		if err := r.consumeCh.Close(); err != nil {
@@ -124,75 +201,331 @@ func (r *RabbitMQ) Close() error {
 	return closeErr
 }
 
-func (r *RabbitMQ) Declare(exchange string, queueName string, bindings []Binding) error {
-	if exchange == "" {
-		return errors.New("exchange is empty")
-	}
-	if queueName == "" {
-		return errors.New("queue name is empty")
+// session is one connection with its channels.
+type session struct {
+	conn       *amqp091.Connection
+	pubCh      *amqp091.Channel
+	pubReturns *publishReturns
+	consumeCh  *amqp091.Channel
+}
+
+func (s session) close() {
+	_ = s.consumeCh.Close()
+	_ = s.pubCh.Close()
+	_ = s.conn.Close()
+}
+
+func (r *RabbitMQ) dial() (session, error) {
+	conn, err := amqp091.Dial(r.url)
+	if err != nil {
+		return session{}, err
 	}
 
-	r.connMu.RLock()
-	ch := r.consumeCh
-	r.connMu.RUnlock()
-	if ch == nil {
-		return errors.New("rabbitmq is not connected")
+	pubCh, err := conn.Channel()
+	if err != nil {
+		_ = conn.Close()
+		return session{}, err
 	}
 
-	q, err := ch.QueueDeclare(queueName, true, false, false, false, nil)
+	consumeCh, err := conn.Channel()
 	if err != nil {
-		return err
+		_ = pubCh.Close()
+		_ = conn.Close()
+		return session{}, err
 	}
 
-	for _, b := range bindings {
-		if b.RoutingKey == "" {
+	s := session{conn: conn, pubCh: pubCh, consumeCh: consumeCh}
+
+	if err := consumeCh.Qos(r.prefetch, 0, false); err != nil {
+		s.close()
+		return session{}, err
+	}
+
+	if r.confirm {
+		if err := pubCh.Confirm(false); err != nil {
+			s.close()
+			return session{}, err
+		}
+	}
+	s.pubReturns = newPublishReturns(pubCh.NotifyReturn(make(chan amqp091.Return, 16)))
+
+	return s, nil
+}
+
+func (r *RabbitMQ) setSession(s session) {
+	r.connMu.Lock()
+	defer r.connMu.Unlock()
+
+	r.conn = s.conn
+	r.pubCh = s.pubCh
+	r.pubReturns = s.pubReturns
+	r.consumeCh = s.consumeCh
+
+	select {
+	case <-r.connected:
+	default:
+		close(r.connected)
+	}
+
+	r.log.WithFields(logrus.Fields{
+		"prefetch":  r.prefetch,
+		"confirm":   r.confirm,
+		"mandatory": r.mandatory,
+	}).Info("RabbitMQ connected")
+}
+
+// watch waits for the connection (or one of its channels) to close and reconnects.
+// A channel closed by a channel error, ex: a failed declaration, also restarts the connection.
+func (r *RabbitMQ) watch(s session) {
+	connClosed := s.conn.NotifyClose(make(chan *amqp091.Error, 1))
+	pubClosed := s.pubCh.NotifyClose(make(chan *amqp091.Error, 1))
+	consumeClosed := s.consumeCh.NotifyClose(make(chan *amqp091.Error, 1))
+
+	var reason *amqp091.Error
+	select {
+	case <-r.done:
+		return
+	case reason = <-connClosed:
+	case reason = <-pubClosed:
+	case reason = <-consumeClosed:
+	}
+
+	r.connMu.Lock()
+	if r.closing {
+		r.connMu.Unlock()
+		return
+	}
+	r.conn = nil
+	r.pubCh = nil
+	r.pubReturns = nil
+	r.consumeCh = nil
+	r.connected = make(chan struct{})
+	r.connMu.Unlock()
+
+	s.close()
+
+	r.log.WithField("reason", reason).Warn("RabbitMQ connection lost, reconnecting")
+	r.reconnect()
+}
+
+// reconnect retries with exponential backoff and jitter until it connects or Close is called.
+// The topology is declared again before the new session is used, consumers then resubscribe
+// (see Consume) and the QoS is restored by dial.
+func (r *RabbitMQ) reconnect() {
+	delay := r.reconnectMinDelay
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-r.done:
+			return
+		case <-time.After(withJitter(delay)):
+		}
+
+		s, err := r.dial()
+		if err == nil {
+			if err = r.declareStoredTopology(s.consumeCh); err != nil {
+				s.close()
+			}
+		}
+		if err != nil {
+			r.log.WithError(err).WithFields(logrus.Fields{
+				"attempt": attempt,
+				"delay":   delay,
+			}).Warn("RabbitMQ reconnection failed")
+			delay = min(delay*2, r.reconnectMaxDelay)
 			continue
 		}
-		if err := ch.QueueBind(q.Name, string(b.RoutingKey), exchange, false, nil); err != nil {
-			return err
+
+		r.connMu.RLock()
+		closing := r.closing
+		r.connMu.RUnlock()
+		if closing {
+			s.close()
+			return
 		}
-	}
 
-	return nil
+		r.setSession(s)
+		go r.watch(s)
+		return
+	}
 }
 
-func (r *RabbitMQ) DeclareExchange(exchange string) error {
-	if exchange == "" {
-		return errors.New("exchange is empty")
+// waitConnected blocks until the client is connected, ctx is done or the client is closed.
+func (r *RabbitMQ) waitConnected(ctx context.Context) error {
+	r.connMu.RLock()
+	connected := r.connected
+	r.connMu.RUnlock()
+
+	select {
+	case <-connected:
+		return nil
+	case <-r.done:
+		return errors.New("rabbitmq is closed")
+	case <-ctx.Done():
+		return ctx.Err()
 	}
+}
 
+func (r *RabbitMQ) Declare(exchange string, queueName string, bindings []Binding) error {
 	r.connMu.RLock()
 	ch := r.consumeCh
 	r.connMu.RUnlock()
 	if ch == nil {
-		return errors.New("rabbitmq is not connected")
+		return ErrNotConnected
 	}
+	return declareQueue(ch, exchange, queueName, bindings)
+}
 
-	return ch.ExchangeDeclare(exchange, "topic", true, false, false, false, nil)
+func (r *RabbitMQ) DeclareExchange(exchange string) error {
+	r.connMu.RLock()
+	ch := r.consumeCh
+	r.connMu.RUnlock()
+	if ch == nil {
+		return ErrNotConnected
+	}
+	return declareExchange(ch, exchange)
 }
 
 type QueueSpec struct {
 	Name     string
 	Bindings []Binding
+	// Retry decides what happens to the messages the handler fails on, the zero value uses DefaultRetryPolicy.
+	Retry RetryPolicy
+	// Concurrency is the number of deliveries handled in parallel (default 1). Keep it at most the prefetch.
+	Concurrency int
+	// PartitionKey is optional. Deliveries with the same key always go to the same worker, so they are
+	// handled in the order they were received. Without it deliveries go to whichever worker is free.
+	PartitionKey func(d Delivery) string
+}
+
+// RetryPolicy sends a failed message to the <queue>.retry queue, where it waits Delay before it
+// goes back to the queue. After MaxAttempts attempts it goes to the <queue>.dlq dead-letter queue.
+type RetryPolicy struct {
+	MaxAttempts int
+	Delay       time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 5, Delay: 10 * time.Second}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = DefaultRetryPolicy.MaxAttempts
+	}
+	if p.Delay <= 0 {
+		p.Delay = DefaultRetryPolicy.Delay
+	}
+	return p
 }
 
 // EnsureTopology ensures the exchange exists, then ensures each queue and its bindings exist.
 // This is safe to call on every startup; declarations are idempotent as long as properties match.
+// The topology is remembered and declared again after every reconnection.
 func (r *RabbitMQ) EnsureTopology(exchange string, queues []QueueSpec) error {
-	if err := r.DeclareExchange(exchange); err != nil {
+	r.topologyMu.Lock()
+	r.topologyExchange = exchange
+	r.topologyQueues = queues
+	r.topologyMu.Unlock()
+
+	r.connMu.RLock()
+	ch := r.consumeCh
+	r.connMu.RUnlock()
+	if ch == nil {
+		return ErrNotConnected
+	}
+	return declareTopology(ch, exchange, queues)
+}
+
+func (r *RabbitMQ) declareStoredTopology(ch *amqp091.Channel) error {
+	r.topologyMu.Lock()
+	exchange := r.topologyExchange
+	queues := r.topologyQueues
+	r.topologyMu.Unlock()
+
+	if exchange == "" {
+		return nil
+	}
+	return declareTopology(ch, exchange, queues)
+}
+
+func declareTopology(ch *amqp091.Channel, exchange string, queues []QueueSpec) error {
+	if err := declareExchange(ch, exchange); err != nil {
+		return err
+	}
+	if err := ch.ExchangeDeclare(DeadLetterExchangeName(exchange), "direct", true, false, false, false, nil); err != nil {
 		return err
 	}
 	for _, q := range queues {
 		if q.Name == "" {
 			continue
 		}
-		if err := r.Declare(exchange, q.Name, q.Bindings); err != nil {
+		if err := declareQueue(ch, exchange, q.Name, q.Bindings); err != nil {
+			return err
+		}
+		if err := declareRetryQueues(ch, exchange, q); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// declareRetryQueues declares <queue>.retry, whose messages expire after the retry delay and are
+// dead-lettered back to the queue through the default exchange, and <queue>.dlq bound to the
+// dead-letter exchange. A queue with a single attempt has no retry queue.
+func declareRetryQueues(ch *amqp091.Channel, exchange string, q QueueSpec) error {
+	policy := q.Retry.withDefaults()
+
+	if policy.MaxAttempts > 1 {
+		_, err := ch.QueueDeclare(RetryQueueName(q.Name), true, false, false, false, amqp091.Table{
+			"x-message-ttl":             policy.Delay.Milliseconds(),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": q.Name,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	dlq, err := ch.QueueDeclare(DeadLetterQueueName(q.Name), true, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+	return ch.QueueBind(dlq.Name, q.Name, DeadLetterExchangeName(exchange), false, nil)
+}
+
+func declareExchange(ch *amqp091.Channel, exchange string) error {
+	if exchange == "" {
+		return errors.New("exchange is empty")
+	}
+	return ch.ExchangeDeclare(exchange, "topic", true, false, false, false, nil)
+}
+
+func declareQueue(ch *amqp091.Channel, exchange string, queueName string, bindings []Binding) error {
+	if exchange == "" {
+		return errors.New("exchange is empty")
+	}
+	if queueName == "" {
+		return errors.New("queue name is empty")
+	}
+
+	q, err := ch.QueueDeclare(queueName, true, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, b := range bindings {
+		if b.RoutingKey == "" {
+			continue
+		}
+		if err := ch.QueueBind(q.Name, string(b.RoutingKey), exchange, false, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Publish sends msg to the broker. In confirm mode it waits for the broker ack and returns
+// ErrPublishNacked, ErrUnroutable (mandatory) or ErrConfirmTimeout when the message was not accepted.
+// While disconnected it fails right away with ErrNotConnected, callers such as the outbox relay retry later.
 func (r *RabbitMQ) Publish(ctx context.Context, msg Message) error {
 	if msg.Exchange == "" {
 		return errors.New("publish exchange is empty")
@@ -204,23 +537,150 @@ func (r *RabbitMQ) Publish(ctx context.Context, msg Message) error {
 		msg.ContentType = "application/json"
 	}
 
-	r.pubMu.Lock()
-	defer r.pubMu.Unlock()
+	ctx, span, msg := startPublishSpan(ctx, "rabbitmq", withCorrelation(ctx, msg))
+	err := r.publish(ctx, msg.Exchange, msg.RoutingKey, toPublishing(msg))
+	endSpan(span, err)
+	return err
+}
 
+func (r *RabbitMQ) publish(ctx context.Context, exchange string, routingKey string, publishing amqp091.Publishing) error {
 	r.connMu.RLock()
 	ch := r.pubCh
+	returns := r.pubReturns
 	r.connMu.RUnlock()
-	if ch == nil {
-		return errors.New("rabbitmq not connected")
+	if ch == nil || ch.IsClosed() {
+		r.stats.failed.Add(1)
+		return ErrNotConnected
+	}
+
+	if !r.confirm {
+		r.pubMu.Lock()
+		err := ch.PublishWithContext(ctx, exchange, routingKey, r.mandatory, false, publishing)
+		r.pubMu.Unlock()
+		r.countPublish(err)
+		return err
+	}
+
+	// The lock is released once the message is sent, the confirm is waited for without it so
+	// a slow confirm doesn't hold the other publishes back.
+	r.pubMu.Lock()
+	confirmation, err := ch.PublishWithDeferredConfirmWithContext(ctx, exchange, routingKey, r.mandatory, false, publishing)
+	r.pubMu.Unlock()
+	if err != nil {
+		r.countPublish(err)
+		return err
+	}
+	r.stats.published.Add(1)
+
+	waitCtx := ctx
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, r.publishTimeout)
+		defer cancel()
+	}
+
+	acked, err := confirmation.WaitContext(waitCtx)
+	if err != nil {
+		r.stats.failed.Add(1)
+		returns.forget(publishing.MessageId)
+		return fmt.Errorf("%w: %w", ErrConfirmTimeout, err)
 	}
 
-	return ch.PublishWithContext(ctx, msg.Exchange, msg.RoutingKey, false, false, amqp091.Publishing{
-		ContentType: msg.ContentType,
-		Body:        msg.Body,
-		Timestamp:   time.Now(),
+	log := r.log.WithFields(logrus.Fields{
+		"exchange":   exchange,
+		"routingKey": routingKey,
 	})
+
+	if !acked {
+		r.stats.nacked.Add(1)
+		log.Warn("RabbitMQ nacked publish")
+		return ErrPublishNacked
+	}
+
+	if ret, ok := returns.take(publishing.MessageId); ok {
+		r.stats.unroutable.Add(1)
+		log.WithFields(logrus.Fields{
+			"replyCode": ret.ReplyCode,
+			"replyText": ret.ReplyText,
+		}).Warn("RabbitMQ returned unroutable message")
+		return fmt.Errorf("%w: %s/%s: %s", ErrUnroutable, ret.Exchange, ret.RoutingKey, ret.ReplyText)
+	}
+
+	r.stats.confirmed.Add(1)
+	return nil
 }
 
+// PublishStats returns the publish counters, see PublishStats.
+func (r *RabbitMQ) PublishStats() PublishStats {
+	return PublishStats{
+		Published:  r.stats.published.Load(),
+		Confirmed:  r.stats.confirmed.Load(),
+		Nacked:     r.stats.nacked.Load(),
+		Unroutable: r.stats.unroutable.Load(),
+		Failed:     r.stats.failed.Load(),
+	}
+}
+
+// ConsumeStats returns the consume counters, see ConsumeStats.
+func (r *RabbitMQ) ConsumeStats() ConsumeStats {
+	return r.consumeStats.snapshot()
+}
+
+func (r *RabbitMQ) countPublish(err error) {
+	if err != nil {
+		r.stats.failed.Add(1)
+		return
+	}
+	r.stats.published.Add(1)
+}
+
+// publishReturns matches the messages the broker returned as unroutable to their publish by message
+// ID. Several publishes wait for their confirm at a time, so a return read by one is kept for its own.
+type publishReturns struct {
+	mu       sync.Mutex
+	ch       <-chan amqp091.Return
+	returned map[string]amqp091.Return
+}
+
+func newPublishReturns(ch <-chan amqp091.Return) *publishReturns {
+	return &publishReturns{ch: ch, returned: map[string]amqp091.Return{}}
+}
+
+// take returns the return of messageID, if any. It must be called once the publish was acked:
+// the broker sends basic.return before the ack of an unroutable mandatory message.
+func (p *publishReturns) take(messageID string) (amqp091.Return, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.collect()
+	ret, ok := p.returned[messageID]
+	delete(p.returned, messageID)
+	return ret, ok
+}
+
+// forget drops the return of a publish that gave up waiting for its confirm.
+func (p *publishReturns) forget(messageID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.collect()
+	delete(p.returned, messageID)
+}
+
+func (p *publishReturns) collect() {
+	for {
+		select {
+		case ret := <-p.ch:
+			p.returned[ret.MessageId] = ret
+		default:
+			return
+		}
+	}
+}
+
+// Consume handles the deliveries of queueName until ctx is done. When the connection drops
+// it waits for the reconnection and subscribes again, so it only returns when ctx is done
+// or the client is closed.
 func (r *RabbitMQ) Consume(ctx context.Context, queueName string, handler RabbitMQHandler) error {
 	if queueName == "" {
 		return errors.New("queue name is empty")
@@ -229,12 +689,39 @@ func (r *RabbitMQ) Consume(ctx context.Context, queueName string, handler Rabbit
 		return errors.New("handler is nil")
 	}
 
-	r.connMu.RLock()
-	ch := r.consumeCh
-	r.connMu.RUnlock()
-	if ch == nil {
-		return errors.New("rabbitmq not connected")
+	for {
+		if err := r.waitConnected(ctx); err != nil {
+			return err
+		}
+
+		r.connMu.RLock()
+		ch := r.consumeCh
+		r.connMu.RUnlock()
+		if ch == nil {
+			continue
+		}
+
+		err := r.consume(ctx, ch, queueName, handler)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		r.log.WithError(err).WithField("queue", queueName).Warn("RabbitMQ consumer stopped, resubscribing after reconnection")
+
+		// Don't spin if the channel is still open but the subscription failed (ex: missing queue).
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-r.done:
+			return errors.New("rabbitmq is closed")
+		case <-time.After(r.reconnectMinDelay):
+		}
 	}
+}
+
+func (r *RabbitMQ) consume(ctx context.Context, ch *amqp091.Channel, queueName string, handler RabbitMQHandler) error {
+	spec := r.queueSpec(queueName)
+	concurrency := max(spec.Concurrency, 1)
 
 	consumerTag := fmt.Sprintf("pharos-%d", time.Now().UnixNano())
 	deliveries, err := ch.Consume(queueName, consumerTag, false, false, false, false, nil)
@@ -242,31 +729,88 @@ func (r *RabbitMQ) Consume(ctx context.Context, queueName string, handler Rabbit
 		return err
 	}
 
-	r.log.WithFields(logrus.Fields{
+	log := r.log.WithFields(logrus.Fields{
 		"queue":       queueName,
 		"consumerTag": consumerTag,
-	}).Info("RabbitMQ consuming")
+		"concurrency": concurrency,
+		"partitioned": spec.PartitionKey != nil,
+	})
+	if concurrency > r.prefetch {
+		log.WithField("prefetch", r.prefetch).Warn("RabbitMQ consumer concurrency is higher than the prefetch, some workers will stay idle")
+	}
+	log.Info("RabbitMQ consuming")
+
+	var partitionKey func(d amqp091.Delivery) string
+	if spec.PartitionKey != nil {
+		partitionKey = func(d amqp091.Delivery) string { return spec.PartitionKey(toDelivery(d)) }
+	}
+
+	pool := newWorkerPool(concurrency, partitionKey, func(d amqp091.Delivery) {
+		// In-flight handlers are not cancelled by ctx, shutdown waits for them instead.
+		r.handleDelivery(context.WithoutCancel(ctx), queueName, d, handler)
+	})
 
 	for {
 		select {
 		case <-ctx.Done():
-			_ = ch.Cancel(consumerTag, false)
+			r.stopConsumer(ch, consumerTag, pool, log)
 			return ctx.Err()
 
 		case d, ok := <-deliveries:
 			if !ok {
+				pool.stop(r.shutdownTimeout)
 				return errors.New("rabbitmq deliveries channel closed")
 			}
 
-			err := handler(ctx, Delivery{
-				Body:       d.Body,
-				RoutingKey: RoutingKey(d.RoutingKey),
-			})
-			if err != nil {
-				d.Nack(false, true)
-				continue
+			if !pool.dispatch(ctx, d) {
+				// ctx was cancelled while every worker was busy.
+				_ = d.Nack(false, true)
+				r.consumeStats.nacked.Add(1)
+				r.stopConsumer(ch, consumerTag, pool, log)
+				return ctx.Err()
 			}
-			d.Ack(false)
 		}
 	}
 }
+
+// stopConsumer waits for the in-flight handlers, so their acks reach the broker, before cancelling the consumer tag.
+func (r *RabbitMQ) stopConsumer(ch *amqp091.Channel, consumerTag string, pool *workerPool[amqp091.Delivery], log *logrus.Entry) {
+	if !pool.stop(r.shutdownTimeout) {
+		log.WithField("timeout", r.shutdownTimeout).Warn("RabbitMQ consumer stopped before in-flight handlers finished")
+	}
+	_ = ch.Cancel(consumerTag, false)
+}
+
+func (r *RabbitMQ) queueSpec(queueName string) QueueSpec {
+	r.topologyMu.Lock()
+	defer r.topologyMu.Unlock()
+
+	for _, q := range r.topologyQueues {
+		if q.Name == queueName {
+			return q
+		}
+	}
+	return QueueSpec{Name: queueName}
+}
+
+// QueueLag returns the number of ready messages of queueName, not yet delivered to a consumer.
+func (r *RabbitMQ) QueueLag(ctx context.Context, queueName string) (int, error) {
+	r.connMu.RLock()
+	conn := r.conn
+	r.connMu.RUnlock()
+	if conn == nil || conn.IsClosed() {
+		return 0, ErrNotConnected
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return 0, err
+	}
+	defer ch.Close()
+
+	q, err := ch.QueueDeclarePassive(queueName, true, false, false, false, nil)
+	if err != nil {
+		return 0, err
+	}
+	return q.Messages, nil
+}
@@ -0,0 +1,245 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/sirupsen/logrus"
+)
+
+// Headers set on retried and dead-lettered messages.
+const (
+	HeaderAttempts              = "x-attempts"
+	HeaderLastError             = "x-last-error"
+	HeaderOriginalExchange      = "x-original-exchange"
+	HeaderOriginalRoutingKey    = "x-original-routing-key"
+	HeaderDeadLetteredAt        = "x-dead-lettered-at"
+	HeaderDeadLetteredFromQueue = "x-dead-lettered-from-queue"
+)
+
+// ErrDeadLetter can be returned (wrapped) by a handler to dead-letter a message right away, without retries.
+var ErrDeadLetter = errors.New("dead-letter message")
+
+func DeadLetterExchangeName(exchange string) string {
+	return exchange + ".dlx"
+}
+
+func DeadLetterQueueName(queueName string) string {
+	return queueName + ".dlq"
+}
+
+func RetryQueueName(queueName string) string {
+	return queueName + ".retry"
+}
+
+// DeadLetter is a message sitting in a dead-letter queue.
+type DeadLetter struct {
+	Body           []byte
+	ContentType    string
+	Exchange       string
+	RoutingKey     RoutingKey
+	Queue          string // the queue the message was dead-lettered from
+	Attempts       int
+	LastError      string
+	DeadLetteredAt time.Time
+	Headers        map[string]any
+}
+
+// handleDelivery runs the handler and acks the delivery. Failed deliveries are republished to the
+// retry queue (or to the dead-letter exchange once the attempts are exhausted) and then acked, so a
+// poison message can't requeue in a hot loop. If the republish fails the delivery is requeued.
+func (r *RabbitMQ) handleDelivery(ctx context.Context, queueName string, d amqp091.Delivery, handler RabbitMQHandler) {
+	r.consumeStats.delivered.Add(1)
+	delivery := toDelivery(d)
+	ctx, span := startProcessSpan(ctx, "rabbitmq", queueName, delivery)
+	err := handler(correlationContext(ctx, delivery), delivery)
+	endSpan(span, err)
+	if err == nil {
+		_ = d.Ack(false)
+		r.consumeStats.acked.Add(1)
+		return
+	}
+
+	policy := r.queueSpec(queueName).Retry.withDefaults()
+	attempts := headerInt(d.Headers, HeaderAttempts) + 1
+
+	log := r.log.WithError(err).WithFields(logrus.Fields{
+		"queue":         queueName,
+		"routingKey":    delivery.RoutingKey,
+		"messageId":     d.MessageId,
+		"correlationId": d.CorrelationId,
+		"attempts":      attempts,
+		"maxAttempts":   policy.MaxAttempts,
+	})
+
+	headers := amqp091.Table{}
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+	headers[HeaderAttempts] = int32(attempts)
+	headers[HeaderLastError] = err.Error()
+	if _, ok := headers[HeaderOriginalExchange]; !ok {
+		headers[HeaderOriginalExchange] = d.Exchange
+		headers[HeaderOriginalRoutingKey] = d.RoutingKey
+	}
+
+	publishing := republishing(d, headers)
+
+	var exchange, routingKey string
+	counter := &r.consumeStats.retried
+	if attempts >= policy.MaxAttempts || errors.Is(err, ErrDeadLetter) {
+		headers[HeaderDeadLetteredAt] = time.Now().UTC().Format(time.RFC3339)
+		headers[HeaderDeadLetteredFromQueue] = queueName
+		exchange, routingKey = DeadLetterExchangeName(r.exchange()), queueName
+		counter = &r.consumeStats.deadLettered
+		log.Error("Message handling failed, dead-lettering")
+	} else {
+		exchange, routingKey = "", RetryQueueName(queueName)
+		log.Warn("Message handling failed, retrying later")
+	}
+
+	if err := r.publish(ctx, exchange, routingKey, publishing); err != nil {
+		log.WithField("republishError", err).Error("Can't republish failed message, requeueing")
+		_ = d.Nack(false, true)
+		r.consumeStats.nacked.Add(1)
+		return
+	}
+	_ = d.Ack(false)
+	r.consumeStats.acked.Add(1)
+	counter.Add(1)
+}
+
+func (r *RabbitMQ) exchange() string {
+	r.topologyMu.Lock()
+	defer r.topologyMu.Unlock()
+	return r.topologyExchange
+}
+
+// InspectDeadLetters returns up to limit messages of the dead-letter queue of queueName
+// without removing them.
+func (r *RabbitMQ) InspectDeadLetters(ctx context.Context, queueName string, limit int) ([]DeadLetter, error) {
+	var deadLetters []DeadLetter
+	err := r.withDeadLetters(ctx, queueName, limit, func(d amqp091.Delivery) error {
+		deadLetters = append(deadLetters, toDeadLetter(d))
+		// Left unacked: the messages go back to the queue when the channel closes.
+		return nil
+	})
+	return deadLetters, err
+}
+
+// ReplayDeadLetters publishes up to limit dead-lettered messages of queueName back to the queue
+// they were dead-lettered from with a fresh attempts count, and returns how many were replayed.
+// They go through the default exchange: republishing to the original exchange would also deliver
+// them again to every other queue bound to it. The original exchange and routing key stay in the
+// headers, the consumer still routes them by the original routing key.
+func (r *RabbitMQ) ReplayDeadLetters(ctx context.Context, queueName string, limit int) (int, error) {
+	replayed := 0
+	err := r.withDeadLetters(ctx, queueName, limit, func(d amqp091.Delivery) error {
+		deadLetter := toDeadLetter(d)
+		target := deadLetter.Queue
+		if target == "" {
+			target = queueName
+		}
+
+		headers := amqp091.Table{}
+		for k, v := range d.Headers {
+			headers[k] = v
+		}
+		for _, k := range []string{HeaderAttempts, HeaderLastError, HeaderDeadLetteredAt, HeaderDeadLetteredFromQueue} {
+			delete(headers, k)
+		}
+
+		err := r.publish(ctx, "", target, republishing(d, headers))
+		if err != nil {
+			return err
+		}
+		if err := d.Ack(false); err != nil {
+			return err
+		}
+		replayed++
+		return nil
+	})
+	return replayed, err
+}
+
+// withDeadLetters gets up to limit messages from the dead-letter queue on a dedicated channel.
+func (r *RabbitMQ) withDeadLetters(ctx context.Context, queueName string, limit int, fn func(d amqp091.Delivery) error) error {
+	r.connMu.RLock()
+	conn := r.conn
+	r.connMu.RUnlock()
+	if conn == nil || conn.IsClosed() {
+		return ErrNotConnected
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+
+	for range limit {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		d, ok, err := ch.Get(DeadLetterQueueName(queueName), false)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+		if err := fn(d); err != nil {
+			return fmt.Errorf("dead letter %d: %w", d.DeliveryTag, err)
+		}
+	}
+	return nil
+}
+
+func toDeadLetter(d amqp091.Delivery) DeadLetter {
+	deadLetter := DeadLetter{
+		Body:        d.Body,
+		ContentType: d.ContentType,
+		Exchange:    headerString(d.Headers, HeaderOriginalExchange),
+		RoutingKey:  RoutingKey(headerString(d.Headers, HeaderOriginalRoutingKey)),
+		Queue:       headerString(d.Headers, HeaderDeadLetteredFromQueue),
+		Attempts:    headerInt(d.Headers, HeaderAttempts),
+		LastError:   headerString(d.Headers, HeaderLastError),
+		Headers:     map[string]any(d.Headers),
+	}
+	if at, err := time.Parse(time.RFC3339, headerString(d.Headers, HeaderDeadLetteredAt)); err == nil {
+		deadLetter.DeadLetteredAt = at
+	}
+	return deadLetter
+}
+
+func headerString(headers amqp091.Table, key string) string {
+	v, _ := headers[key].(string)
+	return v
+}
+
+func headerInt(headers amqp091.Table, key string) int {
+	switch v := headers[key].(type) {
+	case int:
+		return v
+	case int8:
+		return int(v)
+	case int16:
+		return int(v)
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case uint8:
+		return int(v)
+	case uint16:
+		return int(v)
+	case uint32:
+		return int(v)
+	default:
+		return 0
+	}
+}
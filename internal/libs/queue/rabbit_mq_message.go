@@ -0,0 +1,103 @@
+package queue
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// AMQP has no causation ID property, it travels as a header.
+const HeaderCausationID = "x-causation-id"
+
+// headerDeliveryCount is set by quorum queues on redelivered messages.
+const headerDeliveryCount = "x-delivery-count"
+
+// toPublishing maps msg to the AMQP properties, defaulting the message ID and timestamp.
+func toPublishing(msg Message) amqp091.Publishing {
+	headers := amqp091.Table{}
+	for k, v := range msg.Headers {
+		headers[k] = v
+	}
+	if msg.CausationID != "" {
+		headers[HeaderCausationID] = msg.CausationID
+	}
+
+	messageID := msg.MessageID
+	if messageID == "" {
+		messageID = uuid.NewString()
+	}
+	timestamp := msg.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	var expiration string
+	if msg.Expiration > 0 {
+		expiration = strconv.FormatInt(max(msg.Expiration.Milliseconds(), 1), 10)
+	}
+
+	return amqp091.Publishing{
+		Headers:       headers,
+		ContentType:   msg.ContentType,
+		DeliveryMode:  amqp091.Persistent,
+		Priority:      msg.Priority,
+		CorrelationId: msg.CorrelationID,
+		ReplyTo:       msg.ReplyTo,
+		Expiration:    expiration,
+		MessageId:     messageID,
+		Timestamp:     timestamp.UTC(),
+		Type:          msg.Type,
+		Body:          msg.Body,
+	}
+}
+
+// toDelivery maps d to a Delivery. Retried and replayed messages come back through the default
+// exchange with the queue name as routing key, they keep the original ones in headers.
+func toDelivery(d amqp091.Delivery) Delivery {
+	var expiration time.Duration
+	if ms, err := strconv.ParseInt(d.Expiration, 10, 64); err == nil {
+		expiration = time.Duration(ms) * time.Millisecond
+	}
+
+	exchange, routingKey := d.Exchange, d.RoutingKey
+	if original, ok := d.Headers[HeaderOriginalRoutingKey].(string); ok {
+		exchange, routingKey = headerString(d.Headers, HeaderOriginalExchange), original
+	}
+
+	return Delivery{
+		Body:          d.Body,
+		RoutingKey:    RoutingKey(routingKey),
+		Exchange:      exchange,
+		ContentType:   d.ContentType,
+		MessageID:     d.MessageId,
+		CorrelationID: d.CorrelationId,
+		CausationID:   headerString(d.Headers, HeaderCausationID),
+		Headers:       map[string]any(d.Headers),
+		Timestamp:     d.Timestamp,
+		Type:          d.Type,
+		Priority:      d.Priority,
+		Expiration:    expiration,
+		ReplyTo:       d.ReplyTo,
+		Redelivered:   d.Redelivered,
+		DeliveryCount: headerInt(d.Headers, HeaderAttempts) + headerInt(d.Headers, headerDeliveryCount) + 1,
+	}
+}
+
+// republishing copies the properties of d to publish it again with headers. The per-message
+// expiration is dropped, the message already waited in the queue.
+func republishing(d amqp091.Delivery, headers amqp091.Table) amqp091.Publishing {
+	return amqp091.Publishing{
+		Headers:       headers,
+		ContentType:   d.ContentType,
+		DeliveryMode:  amqp091.Persistent,
+		Priority:      d.Priority,
+		CorrelationId: d.CorrelationId,
+		ReplyTo:       d.ReplyTo,
+		MessageId:     d.MessageId,
+		Timestamp:     d.Timestamp,
+		Type:          d.Type,
+		Body:          d.Body,
+	}
+}
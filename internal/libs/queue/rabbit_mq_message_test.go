@@ -0,0 +1,77 @@
+package queue
+
+import (
+	"testing"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+func TestToDeliveryRestoresOriginalRouting(t *testing.T) {
+	tests := []struct {
+		name           string
+		delivery       amqp091.Delivery
+		wantExchange   string
+		wantRoutingKey RoutingKey
+	}{
+		{
+			name:           "first delivery",
+			delivery:       amqp091.Delivery{Exchange: "app", RoutingKey: "users.created"},
+			wantExchange:   "app",
+			wantRoutingKey: "users.created",
+		},
+		{
+			// The retry queue dead-letters back through the default exchange to the queue name.
+			name: "retried delivery",
+			delivery: amqp091.Delivery{
+				Exchange:   "",
+				RoutingKey: "users.events",
+				Headers: amqp091.Table{
+					HeaderAttempts:           int32(1),
+					HeaderOriginalExchange:   "app",
+					HeaderOriginalRoutingKey: "users.created",
+				},
+			},
+			wantExchange:   "app",
+			wantRoutingKey: "users.created",
+		},
+		{
+			name: "replayed dead letter",
+			delivery: amqp091.Delivery{
+				Exchange:   "",
+				RoutingKey: "users.events",
+				Headers: amqp091.Table{
+					HeaderOriginalExchange:   "app",
+					HeaderOriginalRoutingKey: "users.updated",
+				},
+			},
+			wantExchange:   "app",
+			wantRoutingKey: "users.updated",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delivery := toDelivery(tt.delivery)
+			if delivery.Exchange != tt.wantExchange {
+				t.Errorf("exchange = %q, want %q", delivery.Exchange, tt.wantExchange)
+			}
+			if delivery.RoutingKey != tt.wantRoutingKey {
+				t.Errorf("routing key = %q, want %q", delivery.RoutingKey, tt.wantRoutingKey)
+			}
+			// Handlers are routed by pattern, a retried message must still match its route.
+			if !MatchRoutingKey("users.*", delivery.RoutingKey) {
+				t.Errorf("routing key %q doesn't match users.*", delivery.RoutingKey)
+			}
+		})
+	}
+}
+
+func TestToDeliveryCountsAttempts(t *testing.T) {
+	delivery := toDelivery(amqp091.Delivery{
+		RoutingKey: "users.events",
+		Headers:    amqp091.Table{HeaderAttempts: int32(2), HeaderOriginalRoutingKey: "users.created"},
+	})
+	if delivery.DeliveryCount != 3 {
+		t.Errorf("delivery count = %d, want 3", delivery.DeliveryCount)
+	}
+}
@@ -0,0 +1,141 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// DirectReplyToQueue is the RabbitMQ pseudo-queue replies are sent to with direct reply-to.
+const DirectReplyToQueue = "amq.rabbitmq.reply-to"
+
+// rpcSession is the channel requests are published on and replies consumed from. Direct reply-to
+// needs both on the same channel.
+type rpcSession struct {
+	ch      *amqp091.Channel
+	replyTo string
+	// returns gets the requests no queue is bound for, they are published mandatory.
+	returns chan amqp091.Return
+	// closed is closed when the channel stops delivering replies, ex: the connection dropped.
+	closed chan struct{}
+}
+
+// Request publishes msg with a reply-to and waits for the reply until ctx is done. Without a ctx
+// deadline it waits RabbitMQOptions.RequestTimeout. See Requester.
+// Requests are published mandatory: one no queue is bound for fails right away with ErrUnroutable
+// instead of waiting for the timeout.
+func (r *RabbitMQ) Request(ctx context.Context, msg Message) (Delivery, error) {
+	ctx, cancel := withRequestTimeout(ctx, r.requestTimeout)
+	defer cancel()
+
+	session, err := r.rpcSession()
+	if err != nil {
+		return Delivery{}, err
+	}
+
+	msg = prepareRequest(ctx, msg, session.replyTo)
+	reply := r.pending.add(msg.MessageID)
+	defer r.pending.remove(msg.MessageID)
+
+	// The span covers the wait for the reply.
+	ctx, span, msg := startPublishSpan(ctx, "rabbitmq", msg)
+	if err := session.ch.PublishWithContext(ctx, msg.Exchange, msg.RoutingKey, true, false, toPublishing(msg)); err != nil {
+		r.stats.failed.Add(1)
+		endSpan(span, err)
+		return Delivery{}, err
+	}
+	r.stats.published.Add(1)
+
+	d, err := awaitReply(ctx, reply, session.closed)
+	endSpan(span, err)
+	return d, err
+}
+
+// Reply publishes response to the reply-to of request through the default exchange.
+func (r *RabbitMQ) Reply(ctx context.Context, request Delivery, response Message) error {
+	response, err := prepareReply(request, response)
+	if err != nil {
+		return err
+	}
+	return r.publish(ctx, "", response.RoutingKey, toPublishing(response))
+}
+
+// rpcSession returns the open RPC session, or opens one on the current connection. With
+// RabbitMQOptions.DirectReplyTo replies use direct reply-to, otherwise an exclusive callback queue.
+func (r *RabbitMQ) rpcSession() (*rpcSession, error) {
+	r.rpcMu.Lock()
+	defer r.rpcMu.Unlock()
+
+	if r.rpc != nil && !r.rpc.ch.IsClosed() {
+		return r.rpc, nil
+	}
+
+	r.connMu.RLock()
+	conn := r.conn
+	r.connMu.RUnlock()
+	if conn == nil || conn.IsClosed() {
+		return nil, ErrNotConnected
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+
+	replyTo := DirectReplyToQueue
+	if !r.directReplyTo {
+		q, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			_ = ch.Close()
+			return nil, err
+		}
+		replyTo = q.Name
+	}
+
+	// Direct reply-to only works in auto-ack mode, replies are not redelivered anyway.
+	replies, err := ch.Consume(replyTo, "", true, true, false, false, nil)
+	if err != nil {
+		_ = ch.Close()
+		return nil, err
+	}
+
+	session := &rpcSession{
+		ch:      ch,
+		replyTo: replyTo,
+		returns: ch.NotifyReturn(make(chan amqp091.Return, 1)),
+		closed:  make(chan struct{}),
+	}
+	go r.dispatchReplies(session, replies)
+
+	r.rpc = session
+	r.log.WithField("replyTo", replyTo).Info("RabbitMQ RPC session opened")
+	return session, nil
+}
+
+// dispatchReplies hands the replies and the returned requests of session to the waiting requests.
+func (r *RabbitMQ) dispatchReplies(session *rpcSession, replies <-chan amqp091.Delivery) {
+	defer close(session.closed)
+
+	returns := session.returns
+	for {
+		select {
+		case d, ok := <-replies:
+			if !ok {
+				return
+			}
+			reply := toDelivery(d)
+			if !r.pending.resolve(reply) {
+				r.log.WithField("requestId", reply.CausationID).Warn("RabbitMQ reply arrived after its request gave up")
+			}
+
+		case ret, ok := <-returns:
+			if !ok {
+				returns = nil
+				continue
+			}
+			r.stats.unroutable.Add(1)
+			r.pending.fail(ret.MessageId, fmt.Errorf("%w: %s/%s: %s", ErrUnroutable, ret.Exchange, ret.RoutingKey, ret.ReplyText))
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package queue
+
+import (
+	"testing"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// Publishes wait for their confirm concurrently, a return must reach its own publish whoever reads it.
+func TestPublishReturnsMatchTheirPublish(t *testing.T) {
+	ch := make(chan amqp091.Return, 4)
+	returns := newPublishReturns(ch)
+
+	ch <- amqp091.Return{MessageId: "orders", RoutingKey: "orders.created", ReplyText: "NO_ROUTE"}
+	ch <- amqp091.Return{MessageId: "payments", RoutingKey: "payments.created", ReplyText: "NO_ROUTE"}
+
+	if _, ok := returns.take("users"); ok {
+		t.Error("a routed publish got a return")
+	}
+	ret, ok := returns.take("orders")
+	if !ok || ret.RoutingKey != "orders.created" {
+		t.Errorf("take(orders) = %+v, %v", ret, ok)
+	}
+	if _, ok := returns.take("orders"); ok {
+		t.Error("a return was taken twice")
+	}
+
+	returns.forget("payments")
+	if _, ok := returns.take("payments"); ok {
+		t.Error("a forgotten return was kept")
+	}
+}
@@ -0,0 +1,224 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// HeaderRPCError carries the error code of the request handler in the reply.
+const HeaderRPCError = "x-rpc-error"
+
+// Error codes of the replies of failed requests.
+const (
+	RPCCodeInvalidRequest = "invalid_request"
+	RPCCodeNotFound       = "not_found"
+	RPCCodeConflict       = "conflict"
+	RPCCodeForbidden      = "forbidden"
+	RPCCodeTimeout        = "timeout"
+	RPCCodeInternal       = "internal"
+)
+
+// RPCError is a request handler error as the requester sees it: a code and a message written for
+// the caller. The cause stays on the serving side. errors.Is(err, ErrRemote) is true.
+type RPCError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+
+	cause error
+}
+
+func NewRPCError(code string, message string, cause error) *RPCError {
+	return &RPCError{Code: code, Message: message, cause: cause}
+}
+
+func (e *RPCError) Error() string {
+	if e.cause != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Message, e.cause)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func (e *RPCError) Unwrap() []error {
+	if e.cause == nil {
+		return []error{ErrRemote}
+	}
+	return []error{ErrRemote, e.cause}
+}
+
+// RequestJSON sends request as JSON to routingKey and decodes the JSON reply into Resp.
+func RequestJSON[Resp any](ctx context.Context, requester Requester, exchange string, routingKey string, request any) (Resp, error) {
+	var response Resp
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return response, err
+	}
+
+	reply, err := requester.Request(ctx, Message{
+		Exchange:    exchange,
+		RoutingKey:  routingKey,
+		Body:        body,
+		ContentType: "application/json",
+	})
+	if err != nil {
+		return response, err
+	}
+
+	if err := json.Unmarshal(reply.Body, &response); err != nil {
+		return response, fmt.Errorf("decode %s reply: %w", routingKey, err)
+	}
+	return response, nil
+}
+
+// DefaultRequestTimeout bounds the wait for a reply when the request ctx has no deadline.
+const DefaultRequestTimeout = 30 * time.Second
+
+// withRequestTimeout applies timeout to ctx unless it already has a deadline.
+func withRequestTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// ErrorReply is the response sent when the request handler failed. An *RPCError keeps its code and
+// message, a timeout becomes RPCCodeTimeout and any other error RPCCodeInternal: the text of an
+// arbitrary error can leak details the requester must not see.
+func ErrorReply(err error) Message {
+	var rpcErr *RPCError
+	switch {
+	case errors.As(err, &rpcErr):
+	case errors.Is(err, context.DeadlineExceeded):
+		rpcErr = NewRPCError(RPCCodeTimeout, "Request timed out", err)
+	default:
+		rpcErr = NewRPCError(RPCCodeInternal, "Internal error", err)
+	}
+
+	body, _ := json.Marshal(rpcErr)
+	return Message{
+		ContentType: "application/json",
+		Body:        body,
+		Headers:     map[string]any{HeaderRPCError: rpcErr.Code},
+	}
+}
+
+// prepareRequest fills the request properties every backend needs: a message ID to match the reply,
+// the correlation ID of ctx and an expiration, so a request nobody handled in time is dropped.
+func prepareRequest(ctx context.Context, msg Message, replyTo string) Message {
+	if msg.MessageID == "" {
+		msg.MessageID = uuid.NewString()
+	}
+	msg = withCorrelation(ctx, msg)
+	if deadline, ok := ctx.Deadline(); ok && msg.Expiration == 0 {
+		msg.Expiration = max(time.Until(deadline), time.Millisecond)
+	}
+	msg.ReplyTo = replyTo
+	return msg
+}
+
+// prepareReply addresses response to the requester.
+func prepareReply(request Delivery, response Message) (Message, error) {
+	if request.ReplyTo == "" {
+		return Message{}, ErrNoReplyTo
+	}
+	if response.ContentType == "" {
+		response.ContentType = "application/json"
+	}
+	response.RoutingKey = request.ReplyTo
+	response.CorrelationID = request.CorrelationID
+	response.CausationID = request.MessageID
+	return response, nil
+}
+
+// replyError returns the *RPCError of a reply carrying a handler error.
+func replyError(reply Delivery) error {
+	code, ok := reply.Headers[HeaderRPCError]
+	if !ok {
+		return nil
+	}
+
+	rpcErr := &RPCError{}
+	if err := json.Unmarshal(reply.Body, rpcErr); err != nil || rpcErr.Code == "" {
+		rpcErr = &RPCError{Code: fmt.Sprint(code)}
+	}
+	return rpcErr
+}
+
+// pendingReply is the outcome of a request: its reply, or why it can't get one.
+type pendingReply struct {
+	delivery Delivery
+	err      error
+}
+
+// pendingRequests routes the replies to the requests waiting for them, by request message ID.
+type pendingRequests struct {
+	mu       sync.Mutex
+	requests map[string]chan pendingReply
+}
+
+func (p *pendingRequests) add(messageID string) chan pendingReply {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.requests == nil {
+		p.requests = map[string]chan pendingReply{}
+	}
+	reply := make(chan pendingReply, 1)
+	p.requests[messageID] = reply
+	return reply
+}
+
+func (p *pendingRequests) remove(messageID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.requests, messageID)
+}
+
+// resolve hands reply to its request and reports false when nobody waits for it anymore.
+func (p *pendingRequests) resolve(reply Delivery) bool {
+	return p.send(reply.CausationID, pendingReply{delivery: reply})
+}
+
+// fail ends the request messageID with err, ex: the broker returned it as unroutable.
+func (p *pendingRequests) fail(messageID string, err error) bool {
+	return p.send(messageID, pendingReply{err: err})
+}
+
+func (p *pendingRequests) send(messageID string, reply pendingReply) bool {
+	p.mu.Lock()
+	c, ok := p.requests[messageID]
+	p.mu.Unlock()
+	if !ok {
+		return false
+	}
+
+	select {
+	case c <- reply:
+	default:
+	}
+	return true
+}
+
+// awaitReply waits for the reply of the request until ctx is done or closed is closed.
+func awaitReply(ctx context.Context, reply <-chan pendingReply, closed <-chan struct{}) (Delivery, error) {
+	select {
+	case r := <-reply:
+		if r.err != nil {
+			return Delivery{}, r.err
+		}
+		if err := replyError(r.delivery); err != nil {
+			return r.delivery, err
+		}
+		return r.delivery, nil
+	case <-closed:
+		return Delivery{}, fmt.Errorf("%w: reply channel closed", ErrNotConnected)
+	case <-ctx.Done():
+		return Delivery{}, ctx.Err()
+	}
+}
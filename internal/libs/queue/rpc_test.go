@@ -0,0 +1,108 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestErrorReplyCodes(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantCode    string
+		wantMessage string
+	}{
+		{
+			name:        "rpc error",
+			err:         NewRPCError(RPCCodeNotFound, "User not found", errors.New("sql: no rows in result set")),
+			wantCode:    RPCCodeNotFound,
+			wantMessage: "User not found",
+		},
+		{
+			name:        "timeout",
+			err:         context.DeadlineExceeded,
+			wantCode:    RPCCodeTimeout,
+			wantMessage: "Request timed out",
+		},
+		{
+			name:        "any other error",
+			err:         errors.New("pq: password authentication failed for user root"),
+			wantCode:    RPCCodeInternal,
+			wantMessage: "Internal error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reply := ErrorReply(tt.err)
+			if reply.Headers[HeaderRPCError] != tt.wantCode {
+				t.Errorf("code header = %v, want %s", reply.Headers[HeaderRPCError], tt.wantCode)
+			}
+			if strings.Contains(string(reply.Body), "pq:") || strings.Contains(string(reply.Body), "sql:") {
+				t.Errorf("reply leaks the cause: %s", reply.Body)
+			}
+
+			err := replyError(Delivery{Body: reply.Body, Headers: reply.Headers})
+			var rpcErr *RPCError
+			if !errors.As(err, &rpcErr) {
+				t.Fatalf("replyError = %v, want an *RPCError", err)
+			}
+			if !errors.Is(err, ErrRemote) {
+				t.Error("the reply error is not an ErrRemote")
+			}
+			if rpcErr.Code != tt.wantCode || rpcErr.Message != tt.wantMessage {
+				t.Errorf("reply error = %s/%s, want %s/%s", rpcErr.Code, rpcErr.Message, tt.wantCode, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestInMemoryBrokerRequestReply(t *testing.T) {
+	broker := newTestInMemoryBroker(t, InMemoryOptions{}, QueueSpec{
+		Name:     "users.rpc",
+		Bindings: []Binding{{RoutingKey: "users.get"}},
+	})
+	consume(t, broker, "users.rpc", func(ctx context.Context, d Delivery) error {
+		if string(d.Body) == `"missing"` {
+			return broker.Reply(ctx, d, ErrorReply(NewRPCError(RPCCodeNotFound, "User not found", nil)))
+		}
+		return broker.Reply(ctx, d, Message{Body: []byte(`{"id":"1"}`)})
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	reply, err := broker.Request(ctx, Message{Exchange: testExchange, RoutingKey: "users.get", Body: []byte(`"1"`)})
+	if err != nil {
+		t.Fatalf("Request: %v", err)
+	}
+	if string(reply.Body) != `{"id":"1"}` {
+		t.Errorf("reply = %s", reply.Body)
+	}
+
+	_, err = broker.Request(ctx, Message{Exchange: testExchange, RoutingKey: "users.get", Body: []byte(`"missing"`)})
+	var rpcErr *RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Code != RPCCodeNotFound {
+		t.Errorf("Request error = %v, want a not_found RPCError", err)
+	}
+}
+
+// A request nobody is bound for fails right away, even when plain publishes are not mandatory.
+func TestInMemoryBrokerUnroutableRequest(t *testing.T) {
+	broker := newTestInMemoryBroker(t, InMemoryOptions{}, QueueSpec{
+		Name:     "users.rpc",
+		Bindings: []Binding{{RoutingKey: "users.get"}},
+	})
+
+	start := time.Now()
+	_, err := broker.Request(context.Background(), Message{Exchange: testExchange, RoutingKey: "orders.get"})
+	if !errors.Is(err, ErrUnroutable) {
+		t.Errorf("Request error = %v, want ErrUnroutable", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Request took %s to fail", elapsed)
+	}
+}
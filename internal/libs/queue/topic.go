@@ -0,0 +1,34 @@
+package queue
+
+import "strings"
+
+// MatchRoutingKey reports if routingKey matches the topic pattern with the AMQP topic exchange rules:
+// words are separated by dots, `*` matches exactly one word and `#` matches zero or more words.
+func MatchRoutingKey(pattern RoutingKey, routingKey RoutingKey) bool {
+	return matchWords(strings.Split(string(pattern), "."), strings.Split(string(routingKey), "."))
+}
+
+func matchWords(pattern []string, words []string) bool {
+	for i, p := range pattern {
+		switch p {
+		case "#":
+			rest := pattern[i+1:]
+			// # absorbs any number of words, try every split.
+			for j := i; j <= len(words); j++ {
+				if matchWords(rest, words[j:]) {
+					return true
+				}
+			}
+			return false
+		case "*":
+			if i >= len(words) {
+				return false
+			}
+		default:
+			if i >= len(words) || words[i] != p {
+				return false
+			}
+		}
+	}
+	return len(pattern) == len(words)
+}
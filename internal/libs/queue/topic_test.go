@@ -0,0 +1,50 @@
+package queue
+
+import "testing"
+
+func TestMatchRoutingKey(t *testing.T) {
+	tests := []struct {
+		pattern    RoutingKey
+		routingKey RoutingKey
+		want       bool
+	}{
+		{pattern: "users.created", routingKey: "users.created", want: true},
+		{pattern: "users.created", routingKey: "users.updated", want: false},
+		{pattern: "users.created", routingKey: "users.created.v2", want: false},
+
+		{pattern: "users.*", routingKey: "users.created", want: true},
+		{pattern: "users.*", routingKey: "users", want: false},
+		{pattern: "users.*", routingKey: "users.created.v2", want: false},
+		{pattern: "*.created", routingKey: "orders.created", want: true},
+
+		// # matches zero words too.
+		{pattern: "users.#", routingKey: "users", want: true},
+		{pattern: "users.#", routingKey: "users.created", want: true},
+		{pattern: "users.#", routingKey: "users.created.v2", want: true},
+		{pattern: "users.#", routingKey: "orders.created", want: false},
+		{pattern: "#", routingKey: "users.created", want: true},
+		{pattern: "#.created", routingKey: "created", want: true},
+
+		// # in the middle.
+		{pattern: "a.#.b", routingKey: "a.b", want: true},
+		{pattern: "a.#.b", routingKey: "a.x.b", want: true},
+		{pattern: "a.#.b", routingKey: "a.x.y.b", want: true},
+		{pattern: "a.#.b", routingKey: "a.x.y", want: false},
+		{pattern: "a.#.b", routingKey: "a.b.c", want: false},
+		{pattern: "a.#.*", routingKey: "a", want: false},
+		{pattern: "a.#.*", routingKey: "a.x", want: true},
+
+		// Empty words are words.
+		{pattern: "users.*.created", routingKey: "users..created", want: true},
+		{pattern: "users.created", routingKey: "users..created", want: false},
+		{pattern: "users.#", routingKey: "users.", want: true},
+		{pattern: "*", routingKey: "", want: true},
+		{pattern: "users", routingKey: "", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchRoutingKey(tt.pattern, tt.routingKey); got != tt.want {
+			t.Errorf("MatchRoutingKey(%q, %q) = %v, want %v", tt.pattern, tt.routingKey, got, tt.want)
+		}
+	}
+}
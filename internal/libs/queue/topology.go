@@ -1,13 +1,19 @@
 package queue
 
 // OwnedQueues returns the queues this service wants to exist in RabbitMQ.
+// Bindings listed here are kept, the queue transport adds one for every routing key
+// or pattern its handlers are registered for (see QueueTransport.Topology).
 func OwnedQueues() []QueueSpec {
 	return []QueueSpec{
 		{
-			Name: UsersCreatedQueueName,
-			Bindings: []Binding{
-				{RoutingKey: UsersCreatedRoutingKey},
-			},
+			Name:        UsersCreatedQueueName,
+			Concurrency: 4,
+		},
+		{
+			Name:        UsersRPCQueueName,
+			Concurrency: 4,
+			// The requester is waiting, a failed request is answered with an error instead of retried.
+			Retry: RetryPolicy{MaxAttempts: 1},
 		},
 	}
 }
@@ -0,0 +1,72 @@
+package queue
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "go-api-template/queue"
+
+// startPublishSpan starts the producer span of msg and injects its trace context into a copy of the
+// message headers. Without a span in ctx (ex: the outbox relay), the trace context already in the
+// headers, written when the event was recorded, is the parent, so the trace still starts at the request.
+func startPublishSpan(ctx context.Context, system string, msg Message) (context.Context, trace.Span, Message) {
+	headers := make(map[string]any, len(msg.Headers)+2)
+	for k, v := range msg.Headers {
+		headers[k] = v
+	}
+
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, HeaderCarrier(headers))
+	}
+
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "send "+msg.RoutingKey,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKey.String(system),
+			semconv.MessagingOperationTypeSend,
+			semconv.MessagingDestinationNameKey.String(msg.Exchange),
+			semconv.MessagingRabbitMQDestinationRoutingKeyKey.String(msg.RoutingKey),
+			semconv.MessagingMessageIDKey.String(msg.MessageID),
+		),
+	)
+
+	otel.GetTextMapPropagator().Inject(ctx, HeaderCarrier(headers))
+	msg.Headers = headers
+	return ctx, span, msg
+}
+
+// startProcessSpan extracts the trace context of the publisher from the headers of d and starts the
+// consumer span of the handler as its child.
+func startProcessSpan(ctx context.Context, system string, queueName string, d Delivery) (context.Context, trace.Span) {
+	if len(d.Headers) > 0 {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, HeaderCarrier(d.Headers))
+	}
+
+	return otel.Tracer(tracerName).Start(ctx, "process "+string(d.RoutingKey),
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKey.String(system),
+			semconv.MessagingOperationTypeProcess,
+			semconv.MessagingDestinationNameKey.String(d.Exchange),
+			semconv.MessagingConsumerGroupNameKey.String(queueName),
+			semconv.MessagingRabbitMQDestinationRoutingKeyKey.String(string(d.RoutingKey)),
+			semconv.MessagingMessageIDKey.String(d.MessageID),
+			attribute.Int("messaging.delivery_count", d.DeliveryCount),
+		),
+	)
+}
+
+// endSpan records err, if any, and ends span.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
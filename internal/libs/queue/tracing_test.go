@@ -0,0 +1,149 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	// tracing.NewTracerProvider can't be used here, libs/tracing depends on this package through config.
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSampler(sdktrace.AlwaysSample()), sdktrace.WithSpanProcessor(recorder))
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+		_ = tp.Shutdown(context.Background())
+	})
+	return recorder
+}
+
+func spansByKind(recorder *tracetest.SpanRecorder, kind trace.SpanKind) []sdktrace.ReadOnlySpan {
+	var spans []sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.SpanKind() == kind {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+// The consumer span continues the trace of the publisher through the message headers.
+func TestBrokerSpansFollowTheMessage(t *testing.T) {
+	recorder := recordSpans(t)
+	broker := newTestInMemoryBroker(t, InMemoryOptions{}, QueueSpec{
+		Name:     "users",
+		Bindings: []Binding{{RoutingKey: "users.*"}},
+		Retry:    RetryPolicy{MaxAttempts: 1},
+	})
+
+	handled := make(chan trace.SpanContext, 2)
+	consume(t, broker, "users", func(ctx context.Context, d Delivery) error {
+		handled <- trace.SpanContextFromContext(ctx)
+		if d.RoutingKey == "users.deleted" {
+			return errors.New("boom")
+		}
+		return nil
+	})
+
+	ctx, request := otel.Tracer("test").Start(context.Background(), "request")
+	for _, key := range []string{"users.created", "users.deleted"} {
+		if err := broker.Publish(ctx, Message{Exchange: testExchange, RoutingKey: key, MessageID: key}); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+	}
+	request.End()
+
+	for range 2 {
+		select {
+		case <-handled:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the handler")
+		}
+	}
+	waitFor(t, "consumer spans", func() bool { return len(spansByKind(recorder, trace.SpanKindConsumer)) == 2 })
+
+	producers := spansByKind(recorder, trace.SpanKindProducer)
+	consumers := spansByKind(recorder, trace.SpanKindConsumer)
+	if len(producers) != 2 {
+		t.Fatalf("got %d producer spans, want 2", len(producers))
+	}
+
+	for i, producer := range producers {
+		if producer.Parent().SpanID() != request.SpanContext().SpanID() {
+			t.Errorf("%s is not a child of the request span", producer.Name())
+		}
+
+		var consumer sdktrace.ReadOnlySpan
+		for _, c := range consumers {
+			if c.Parent().SpanID() == producer.SpanContext().SpanID() {
+				consumer = c
+			}
+		}
+		if consumer == nil {
+			t.Fatalf("no consumer span is a child of %s", producer.Name())
+		}
+		if consumer.SpanContext().TraceID() != request.SpanContext().TraceID() {
+			t.Errorf("%s is not in the request trace", consumer.Name())
+		}
+		if !hasSpanAttribute(consumer, semconv.MessagingSystemKey.String("in-memory")) ||
+			!hasSpanAttribute(consumer, semconv.MessagingConsumerGroupNameKey.String("users")) {
+			t.Errorf("%s attributes = %v", consumer.Name(), consumer.Attributes())
+		}
+
+		wantFailed := i == 1
+		if failed := consumer.Status().Code == codes.Error; failed != wantFailed {
+			t.Errorf("%s failed = %t, want %t", consumer.Name(), failed, wantFailed)
+		}
+	}
+	if producers[0].Name() != "send users.created" {
+		t.Errorf("producer span name = %q, want send users.created", producers[0].Name())
+	}
+}
+
+// Without a span in ctx, ex: the outbox relay, the publish continues the trace stored in the headers.
+func TestPublishSpanContinuesTheTraceInHeaders(t *testing.T) {
+	recorder := recordSpans(t)
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	_, span, msg := startPublishSpan(context.Background(), "test", Message{
+		Exchange:   testExchange,
+		RoutingKey: "users.created",
+		Headers:    map[string]any{"traceparent": "00-" + traceID + "-00f067aa0ba902b7-01"},
+	})
+	span.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	if spans[0].SpanContext().TraceID().String() != traceID {
+		t.Errorf("trace ID = %s, want %s", spans[0].SpanContext().TraceID(), traceID)
+	}
+	if msg.Headers["traceparent"] == "00-"+traceID+"-00f067aa0ba902b7-01" {
+		t.Error("the headers still carry the parent instead of the publish span")
+	}
+}
+
+func hasSpanAttribute(span sdktrace.ReadOnlySpan, want attribute.KeyValue) bool {
+	for _, attr := range span.Attributes() {
+		if attr == want {
+			return true
+		}
+	}
+	return false
+}
@@ -1,11 +1,21 @@
 package queue
 
-import "context"
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
 
 const EventsExchangeName = "go-api-template"
 
 const UsersCreatedQueueName = "users.created"
 const UsersCreatedRoutingKey RoutingKey = "users.created"
+const UsersUpdatedRoutingKey RoutingKey = "users.updated"
+const UsersDeletedRoutingKey RoutingKey = "users.deleted"
+
+// Requests answered by this service, see Requester.
+const UsersRPCQueueName = "users.rpc"
+const UsersGetRoutingKey RoutingKey = "users.get"
 
 // RabbitMQ Publisher types
 
@@ -14,6 +24,24 @@ type Message struct {
 	RoutingKey  string
 	Body        []byte
 	ContentType string
+
+	// MessageID identifies the message, it is generated when empty. Keep it stable across
+	// publish retries so consumers can recognize duplicates.
+	MessageID string
+	// CorrelationID is shared by every message caused by the same originating request.
+	CorrelationID string
+	// CausationID is the ID of the message (or request) that caused this one.
+	CausationID string
+	Headers     map[string]any
+	// Timestamp defaults to the publish time.
+	Timestamp time.Time
+	// Type is the event name, ex: users.created.
+	Type     string
+	Priority uint8
+	// Expiration drops the message if it is not consumed in time, zero never expires.
+	Expiration time.Duration
+	// ReplyTo is the queue the consumer should send its response to.
+	ReplyTo string
 }
 
 type Publisher interface {
@@ -29,8 +57,26 @@ type Binding struct {
 }
 
 type Delivery struct {
-	Body       []byte
-	RoutingKey RoutingKey
+	Body        []byte
+	RoutingKey  RoutingKey
+	Exchange    string
+	ContentType string
+
+	MessageID     string
+	CorrelationID string
+	CausationID   string
+	Headers       map[string]any
+	Timestamp     time.Time
+	Type          string
+	Priority      uint8
+	Expiration    time.Duration
+	ReplyTo       string
+
+	// Redelivered is set by the broker when the message was delivered before without being acked.
+	Redelivered bool
+	// DeliveryCount is how many times the message was delivered, this delivery included. It counts
+	// the retries done through the retry queue and, on quorum queues, the broker redeliveries.
+	DeliveryCount int
 }
 
 type RabbitMQHandler func(ctx context.Context, d Delivery) error
@@ -38,3 +84,70 @@ type RabbitMQHandler func(ctx context.Context, d Delivery) error
 type Consumer interface {
 	Consume(ctx context.Context, queueName string, handler RabbitMQHandler) error
 }
+
+// TopologyManager declares the exchange and queues with their bindings, see RabbitMQ.EnsureTopology.
+type TopologyManager interface {
+	EnsureTopology(exchange string, queues []QueueSpec) error
+}
+
+// Requester sends a request and waits for its reply, until ctx is done. The request gets a reply-to
+// and keeps the correlation ID of ctx; the reply's causation ID is the request message ID. A reply
+// carrying a handler error is returned with ErrRemote.
+type Requester interface {
+	Request(ctx context.Context, msg Message) (Delivery, error)
+}
+
+// Replier sends the response of a request to its reply-to, see Requester.
+type Replier interface {
+	Reply(ctx context.Context, request Delivery, response Message) error
+}
+
+// LagReporter is implemented by the brokers that can tell how many messages wait in a queue.
+type LagReporter interface {
+	QueueLag(ctx context.Context, queueName string) (int, error)
+}
+
+// ConsumeStats are the consume counters of a broker since it was created. A failed delivery is acked
+// once republished for retry or dead-lettering, and nacked (redelivered) when that failed.
+type ConsumeStats struct {
+	Delivered    int64
+	Acked        int64
+	Nacked       int64
+	Retried      int64
+	DeadLettered int64
+}
+
+// ConsumeStatsReporter is implemented by the brokers counting their deliveries.
+type ConsumeStatsReporter interface {
+	ConsumeStats() ConsumeStats
+}
+
+type consumeCounters struct {
+	delivered    atomic.Int64
+	acked        atomic.Int64
+	nacked       atomic.Int64
+	retried      atomic.Int64
+	deadLettered atomic.Int64
+}
+
+func (c *consumeCounters) snapshot() ConsumeStats {
+	return ConsumeStats{
+		Delivered:    c.delivered.Load(),
+		Acked:        c.acked.Load(),
+		Nacked:       c.nacked.Load(),
+		Retried:      c.retried.Load(),
+		DeadLettered: c.deadLettered.Load(),
+	}
+}
+
+// Broker is a message broker backend: RabbitMQ, NATS JetStream or the in-memory broker.
+// Exchanges, queues and routing keys are mapped to the backend's own concepts, so the
+// rest of the code is the same whichever backend is configured.
+type Broker interface {
+	Publisher
+	Consumer
+	TopologyManager
+	Requester
+	Replier
+	Close() error
+}
@@ -0,0 +1,89 @@
+package queue
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// workerPool handles the deliveries (T is the broker delivery type) of one consumer with a fixed
+// number of goroutines. Each worker acks the delivery it handled.
+type workerPool[T any] struct {
+	// Without a partition key every worker reads the shared channel,
+	// with one each worker reads its own channel.
+	shared       chan T
+	partitions   []chan T
+	partitionKey func(d T) string
+
+	wg sync.WaitGroup
+}
+
+func newWorkerPool[T any](size int, partitionKey func(d T) string, handle func(d T)) *workerPool[T] {
+	p := &workerPool[T]{partitionKey: partitionKey}
+
+	work := func(deliveries <-chan T) {
+		defer p.wg.Done()
+		for d := range deliveries {
+			handle(d)
+		}
+	}
+
+	if partitionKey == nil {
+		p.shared = make(chan T)
+		for range size {
+			p.wg.Add(1)
+			go work(p.shared)
+		}
+		return p
+	}
+
+	p.partitions = make([]chan T, size)
+	for i := range p.partitions {
+		p.partitions[i] = make(chan T)
+		p.wg.Add(1)
+		go work(p.partitions[i])
+	}
+	return p
+}
+
+// dispatch blocks until a worker takes d. It returns false if ctx is done first.
+func (p *workerPool[T]) dispatch(ctx context.Context, d T) bool {
+	target := p.shared
+	if p.partitionKey != nil {
+		key := p.partitionKey(d)
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(key))
+		target = p.partitions[h.Sum32()%uint32(len(p.partitions))]
+	}
+
+	select {
+	case target <- d:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// stop lets the workers finish their in-flight deliveries and reports if they did within timeout.
+func (p *workerPool[T]) stop(timeout time.Duration) bool {
+	if p.shared != nil {
+		close(p.shared)
+	}
+	for _, c := range p.partitions {
+		close(c)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
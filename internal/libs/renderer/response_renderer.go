@@ -3,6 +3,8 @@ package renderer
 import (
 	"errors"
 	errs "go-api-template/internal/errors"
+	"go-api-template/internal/libs/correlation"
+	"go-api-template/internal/libs/pagination"
 	"net/http"
 	"time"
 
@@ -12,18 +14,22 @@ import (
 // SuccessfulResponse standardizes responses with 200-299 status code
 type SuccessfulResponse struct {
 	Data      any       `json:"data"`
+	Meta      any       `json:"meta,omitempty"`
+	RequestID string    `json:"requestId,omitempty"`
 	Timestamp time.Time `json:"timestamp"`
 }
 
 // ClientErrorResponse standardizes responses with 400-499 status code
 type ClientErrorResponse struct {
 	errs.HTTPError
+	RequestID string    `json:"requestId,omitempty"`
 	Timestamp time.Time `json:"timestamp"`
 }
 
 // ServerErrorResponse standardizes responses with 500-599 status code
 type ServerErrorResponse struct {
 	errs.HTTPError
+	RequestID string    `json:"requestId,omitempty"`
 	Timestamp time.Time `json:"timestamp"`
 }
 
@@ -49,7 +55,7 @@ func (s *ResponseRenderer) JSON(w http.ResponseWriter, statusCode int, response
 	}
 
 	if statusCode/100 == 2 {
-		response := SuccessfulResponse{Data: response, Timestamp: time.Now().UTC()}
+		response := SuccessfulResponse{Data: response, RequestID: requestID(w), Timestamp: time.Now().UTC()}
 		err := s.Render.JSON(w, statusCode, response)
 		if err != nil {
 			panic(err)
@@ -64,6 +70,32 @@ func (s *ResponseRenderer) JSON(w http.ResponseWriter, statusCode int, response
 	}
 }
 
+// JSONPage renders a page of a list endpoint with the pagination metadata in meta
+// and the RFC 8288 Link header (first, prev, next).
+func (s *ResponseRenderer) JSONPage(w http.ResponseWriter, r *http.Request, statusCode int, data any, page pagination.Page) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if link := page.LinkHeader(r.URL); link != "" {
+		w.Header().Set("Link", link)
+	}
+
+	response := SuccessfulResponse{Data: data, Meta: page, RequestID: requestID(w), Timestamp: time.Now().UTC()}
+	if err := s.Render.JSON(w, statusCode, response); err != nil {
+		panic(err)
+	}
+}
+
+// JSONData renders data in the SuccessfulResponse envelope whatever the status code, for responses
+// that describe a state rather than an error, ex: a failed readiness check is a 503 with the check results.
+func (s *ResponseRenderer) JSONData(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+
+	response := SuccessfulResponse{Data: data, RequestID: requestID(w), Timestamp: time.Now().UTC()}
+	if err := s.Render.JSON(w, statusCode, response); err != nil {
+		panic(err)
+	}
+}
+
 // Other formats... (xml, html etc)
 
 // JSONCustomHTTPError renders errors produced by the app (errs.HTTPError) in a consistent envelope.
@@ -77,7 +109,7 @@ func (s *ResponseRenderer) JSONCustomHTTPError(w http.ResponseWriter, httpErr *e
 	codeClass := statusCode / 100
 
 	if codeClass == 4 {
-		response := ClientErrorResponse{HTTPError: *httpErr, Timestamp: time.Now().UTC()}
+		response := ClientErrorResponse{HTTPError: *httpErr, RequestID: requestID(w), Timestamp: time.Now().UTC()}
 		if err := s.Render.JSON(w, statusCode, response); err != nil {
 			panic(err)
 		}
@@ -94,13 +126,18 @@ func (s *ResponseRenderer) JSONInternalError(w http.ResponseWriter) {
 		Message:    "Internal server error.",
 	}
 
-	response := ServerErrorResponse{HTTPError: httpError, Timestamp: time.Now().UTC()}
+	response := ServerErrorResponse{HTTPError: httpError, RequestID: requestID(w), Timestamp: time.Now().UTC()}
 
 	if err := s.Render.JSON(w, httpError.StatusCode, response); err != nil {
 		panic(err)
 	}
 }
 
+// requestID returns the request ID the request ID middleware echoed in the response headers.
+func requestID(w http.ResponseWriter) string {
+	return w.Header().Get(correlation.RequestIDHeader)
+}
+
 func asHTTPError(v any) (*errs.HTTPError, bool) {
 	if v == nil {
 		return nil, false
@@ -0,0 +1,60 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"go-api-template/config"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+// Setup installs the W3C trace context and baggage propagator and a tracer provider exporting to the
+// exporter of TRACING_EXPORTER. With none, spans are not recorded but the trace context still travels
+// through requests and messages. The returned function flushes and stops the exporter.
+func Setup(ctx context.Context) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch config.CONFIG.TracingExporter {
+	case config.TracingExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case config.TracingExporterOTLP:
+		// The endpoint and headers come from the standard OTEL_EXPORTER_OTLP_* variables.
+		exporter, err = otlptracehttp.New(ctx)
+	case config.TracingExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("unknown TRACING_EXPORTER %q", config.CONFIG.TracingExporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tp := NewTracerProvider(sdktrace.WithBatcher(exporter))
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+// NewTracerProvider returns a tracer provider describing this service and sampling by TRACING_SAMPLE_RATIO.
+// Tests install one with a tracetest.SpanRecorder (sdktrace.WithSpanProcessor) and sdktrace.AlwaysSample
+// to assert on the spans, see the HTTP middleware and database tracing tests.
+func NewTracerProvider(opts ...sdktrace.TracerProviderOption) *sdktrace.TracerProvider {
+	res := resource.NewSchemaless(
+		semconv.ServiceNameKey.String(config.CONFIG.TracingServiceName),
+		semconv.ServiceVersionKey.String(config.CONFIG.Version),
+		semconv.DeploymentEnvironmentNameKey.String(string(config.CONFIG.Env)),
+	)
+
+	opts = append([]sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.CONFIG.TracingSampleRatio))),
+	}, opts...)
+	return sdktrace.NewTracerProvider(opts...)
+}
@@ -2,14 +2,66 @@ package utils
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	errs "go-api-template/internal/errors"
+	"go-api-template/internal/libs/validation"
+	"io"
 	"net/http"
+	"strings"
 )
 
+// MaxJSONBodySize is the largest request body GetJSONBody accepts.
+const MaxJSONBodySize int64 = 1 << 20
+
+// GetJSONBody decodes a single JSON object from the request body and validates it with its `validate` tags.
+// Unknown fields, trailing data and bodies larger than MaxJSONBodySize are rejected.
 func GetJSONBody[T any](r *http.Request) (T, error) {
 	var body T
-	err := json.NewDecoder(r.Body).Decode(&body)
-	if err != nil {
+
+	decoder := json.NewDecoder(http.MaxBytesReader(nil, r.Body, MaxJSONBodySize))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&body); err != nil {
+		return body, toDecodeError(err)
+	}
+	if err := decoder.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return body, errs.NewRequestBodyTooLargeError(maxBytesErr.Limit)
+		}
+		return body, errs.NewInvalidRequestBodyError("Request body must only contain a single JSON object")
+	}
+
+	if err := validation.Struct(body); err != nil {
 		return body, err
 	}
 	return body, nil
 }
+
+func toDecodeError(err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var maxBytesErr *http.MaxBytesError
+
+	switch {
+	case errors.Is(err, io.EOF):
+		return errs.NewInvalidRequestBodyError("Request body is empty")
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return errs.NewInvalidRequestBodyError("Request body contains malformed JSON")
+	case errors.As(err, &syntaxErr):
+		return errs.NewInvalidRequestBodyError(fmt.Sprintf("Request body contains malformed JSON (at position %d)", syntaxErr.Offset))
+	case errors.As(err, &typeErr):
+		if typeErr.Field != "" {
+			return errs.NewInvalidRequestBodyError(fmt.Sprintf("Request body field %q has an invalid type", typeErr.Field))
+		}
+		return errs.NewInvalidRequestBodyError("Request body has an invalid type")
+	case errors.As(err, &maxBytesErr):
+		return errs.NewRequestBodyTooLargeError(maxBytesErr.Limit)
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.TrimPrefix(err.Error(), "json: unknown field ")
+		return errs.NewInvalidRequestBodyError(fmt.Sprintf("Request body contains unknown field %s", field))
+	default:
+		return errs.NewInvalidRequestBodyError("Request body is invalid")
+	}
+}
@@ -0,0 +1,14 @@
+package utils
+
+import "time"
+
+func ParseDurationWithDefault(s string, defaultValue time.Duration) time.Duration {
+	if s == "" {
+		return defaultValue
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return defaultValue
+	}
+	return v
+}
@@ -0,0 +1,14 @@
+package utils
+
+import "strconv"
+
+func ParseFloatWithDefault(s string, defaultValue float64) float64 {
+	if s == "" {
+		return defaultValue
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return defaultValue
+	}
+	return v
+}
@@ -0,0 +1,129 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	errs "go-api-template/internal/errors"
+	"go-api-template/internal/model"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+
+	// Report fields by their json name, that's what clients send.
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+
+	// Optional fields are validated by their value, a missing value is skipped by omitempty.
+	// A present value is returned as a pointer so omitempty doesn't skip it when it's empty,
+	// ex: {"firstName": ""} still has to pass min=1.
+	v.RegisterCustomTypeFunc(func(field reflect.Value) any {
+		if ns, ok := field.Interface().(model.NullString); ok && ns.Valid {
+			return &ns.String
+		}
+		return nil
+	}, model.NullString{})
+	v.RegisterCustomTypeFunc(func(field reflect.Value) any {
+		if ni, ok := field.Interface().(model.NullInt64); ok && ni.Valid {
+			return &ni.Int64
+		}
+		return nil
+	}, model.NullInt64{})
+
+	return v
+}
+
+// Struct validates a struct using its `validate` tags (https://pkg.go.dev/github.com/go-playground/validator/v10).
+// Failures are returned as a 422 errs.HTTPError listing every invalid field.
+func Struct(s any) error {
+	value := reflect.ValueOf(s)
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+
+	err := validate.Struct(s)
+	if err == nil {
+		return nil
+	}
+
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return err
+	}
+
+	fields := make([]errs.FieldError, 0, len(validationErrors))
+	for _, fe := range validationErrors {
+		fields = append(fields, errs.FieldError{
+			Field:   fieldPath(fe),
+			Rule:    fe.Tag(),
+			Message: message(fe),
+		})
+	}
+	return errs.NewValidationFailedError(fields)
+}
+
+// fieldPath drops the root struct name, ex: CreateUserRequest.email -> email.
+func fieldPath(fe validator.FieldError) string {
+	_, path, found := strings.Cut(fe.Namespace(), ".")
+	if !found {
+		return fe.Field()
+	}
+	return path
+}
+
+func message(fe validator.FieldError) string {
+	isString := fe.Kind() == reflect.String
+
+	switch fe.Tag() {
+	case "required", "required_if", "required_unless", "required_with", "required_without":
+		return "is required"
+	case "email":
+		return "must be a valid email"
+	case "uuid", "uuid4":
+		return "must be a valid UUID"
+	case "url":
+		return "must be a valid URL"
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", strings.ReplaceAll(fe.Param(), " ", ", "))
+	case "min":
+		if isString {
+			return fmt.Sprintf("must be at least %s characters long", fe.Param())
+		}
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "max":
+		if isString {
+			return fmt.Sprintf("must be at most %s characters long", fe.Param())
+		}
+		return fmt.Sprintf("must be at most %s", fe.Param())
+	case "len":
+		if isString {
+			return fmt.Sprintf("must be exactly %s characters long", fe.Param())
+		}
+		return fmt.Sprintf("must have exactly %s items", fe.Param())
+	case "gt", "gte", "lt", "lte":
+		operators := map[string]string{"gt": "greater than", "gte": "greater than or equal to", "lt": "less than", "lte": "less than or equal to"}
+		return fmt.Sprintf("must be %s %s", operators[fe.Tag()], fe.Param())
+	default:
+		return fmt.Sprintf("failed the %s rule", fe.Tag())
+	}
+}
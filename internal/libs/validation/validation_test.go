@@ -0,0 +1,119 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	errs "go-api-template/internal/errors"
+	"go-api-template/internal/model"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+type testAddress struct {
+	City string `json:"city" validate:"required"`
+}
+
+type testRequest struct {
+	Name    model.NullString `json:"name" validate:"omitempty,min=1,max=5"`
+	Age     model.NullInt64  `json:"age" validate:"omitempty,gte=18"`
+	Email   string           `json:"email" validate:"required,email"`
+	Address *testAddress     `json:"address" validate:"omitempty"`
+	NoTag   string           `validate:"omitempty,min=2"`
+}
+
+// fieldErrors decodes body into a testRequest and returns the invalid fields.
+func fieldErrors(t *testing.T, body string) []errs.FieldError {
+	t.Helper()
+	var request testRequest
+	if err := json.Unmarshal([]byte(body), &request); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	err := Struct(&request)
+	if err == nil {
+		return nil
+	}
+	var httpErr *errs.HTTPError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("Struct = %v, want an *HTTPError", err)
+	}
+	if httpErr.StatusCode != http.StatusUnprocessableEntity || httpErr.ErrorCode != errs.ValidationFailed {
+		t.Errorf("error = %d/%d, want a 422 validation failed", httpErr.StatusCode, httpErr.ErrorCode)
+	}
+	return httpErr.Fields
+}
+
+func TestStructOptionalFields(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []errs.FieldError
+	}{
+		{name: "omitted", body: `{"email":"a@b.co"}`},
+		{name: "null", body: `{"email":"a@b.co","name":null,"age":null}`},
+		{name: "valid", body: `{"email":"a@b.co","name":"Ada","age":36}`},
+		{
+			// The custom type func returns a pointer, so omitempty doesn't skip a present empty value.
+			name: "present but empty",
+			body: `{"email":"a@b.co","name":"","age":0}`,
+			want: []errs.FieldError{
+				{Field: "name", Rule: "min", Message: "must be at least 1 characters long"},
+				{Field: "age", Rule: "gte", Message: "must be greater than or equal to 18"},
+			},
+		},
+		{
+			name: "too long",
+			body: `{"email":"a@b.co","name":"Lovelace"}`,
+			want: []errs.FieldError{{Field: "name", Rule: "max", Message: "must be at most 5 characters long"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fieldErrors(t, tt.body); !slices.Equal(got, tt.want) {
+				t.Errorf("fields = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStructReportsJSONFieldNames(t *testing.T) {
+	got := fieldErrors(t, `{"email":"not-an-email","address":{}}`)
+	want := []errs.FieldError{
+		{Field: "email", Rule: "email", Message: "must be a valid email"},
+		{Field: "address.city", Rule: "required", Message: "is required"},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("fields = %+v, want %+v", got, want)
+	}
+
+	// Fields without a json name keep their Go name.
+	request := testRequest{Email: "a@b.co", NoTag: "x"}
+	var httpErr *errs.HTTPError
+	if err := Struct(request); !errors.As(err, &httpErr) || len(httpErr.Fields) != 1 || httpErr.Fields[0].Field != "NoTag" {
+		t.Errorf("Struct = %v, want a NoTag field error", err)
+	}
+}
+
+func TestStructRendersFieldsInTheBody(t *testing.T) {
+	err := Struct(&testRequest{})
+	body, jsonErr := json.Marshal(err)
+	if jsonErr != nil {
+		t.Fatalf("marshal: %v", jsonErr)
+	}
+	want := `{"errorCode":7,"statusCode":422,"message":"Validation failed","fields":[{"field":"email","rule":"required","message":"is required"}]}`
+	if string(body) != want {
+		t.Errorf("body = %s\nwant   %s", body, want)
+	}
+}
+
+func TestStructIgnoresNilAndNonStructs(t *testing.T) {
+	var request *testRequest
+	if err := Struct(request); err != nil {
+		t.Errorf("Struct(nil pointer) = %v", err)
+	}
+	if err := Struct("not a struct"); err != nil {
+		t.Errorf("Struct(string) = %v", err)
+	}
+}
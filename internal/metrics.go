@@ -0,0 +1,101 @@
+package internal
+
+import (
+	"go-api-template/config"
+	"go-api-template/internal/libs/metrics"
+	"go-api-template/internal/libs/queue"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// registerMetrics exposes the build info and the stats of the database pool, the outbox relay and the
+// broker on s.Metrics. They are read from the components on every scrape.
+func (s *Server) registerMetrics() {
+	m := s.Metrics
+	m.RegisterBuildInfo(config.CONFIG.Version, string(config.CONFIG.Env))
+	m.RegisterDBStats(s.PostgresDB.Database.DB, "postgres")
+
+	m.RegisterGaugeFunc("outbox_pending_events", "Events of the outbox not published yet.", func() float64 {
+		return float64(s.OutboxRelay.Stats().Pending)
+	})
+	m.RegisterGaugeFunc("outbox_lag_seconds", "Age of the oldest event of the outbox not published yet.", func() float64 {
+		return s.OutboxRelay.Stats().Lag.Seconds()
+	})
+	m.RegisterCounterFunc("outbox_published_total", "Events published by the outbox relay.", func() float64 {
+		return float64(s.OutboxRelay.Stats().Published)
+	})
+	m.RegisterCounterFunc("outbox_failed_total", "Event publishes of the outbox relay that failed.", func() float64 {
+		return float64(s.OutboxRelay.Stats().Failed)
+	})
+
+	if s.RabbitMQ != nil {
+		rabbit := s.RabbitMQ
+		m.RegisterCounterFunc("rabbitmq_published_total", "Messages published to RabbitMQ.", func() float64 {
+			return float64(rabbit.PublishStats().Published)
+		})
+		m.RegisterCounterFunc("rabbitmq_publish_confirmed_total", "Publishes confirmed by RabbitMQ.", func() float64 {
+			return float64(rabbit.PublishStats().Confirmed)
+		})
+		m.RegisterCounterFunc("rabbitmq_publish_nacked_total", "Publishes nacked by RabbitMQ.", func() float64 {
+			return float64(rabbit.PublishStats().Nacked)
+		})
+		m.RegisterCounterFunc("rabbitmq_publish_unroutable_total", "Mandatory publishes returned as unroutable.", func() float64 {
+			return float64(rabbit.PublishStats().Unroutable)
+		})
+		m.RegisterCounterFunc("rabbitmq_publish_failed_total", "Publishes that failed before reaching RabbitMQ.", func() float64 {
+			return float64(rabbit.PublishStats().Failed)
+		})
+	}
+
+	if reporter, ok := s.Broker.(queue.ConsumeStatsReporter); ok {
+		m.RegisterCounterFunc("queue_consumed_total", "Messages delivered to the consumers.", func() float64 {
+			return float64(reporter.ConsumeStats().Delivered)
+		})
+		m.RegisterCounterFunc("queue_acked_total", "Deliveries acked, handled or republished for retry.", func() float64 {
+			return float64(reporter.ConsumeStats().Acked)
+		})
+		m.RegisterCounterFunc("queue_nacked_total", "Deliveries nacked and redelivered.", func() float64 {
+			return float64(reporter.ConsumeStats().Nacked)
+		})
+		m.RegisterCounterFunc("queue_retried_total", "Failed deliveries scheduled for a retry.", func() float64 {
+			return float64(reporter.ConsumeStats().Retried)
+		})
+		m.RegisterCounterFunc("queue_dead_lettered_total", "Failed deliveries dead-lettered.", func() float64 {
+			return float64(reporter.ConsumeStats().DeadLettered)
+		})
+	}
+
+	if reporter, ok := s.Broker.(queue.LagReporter); ok {
+		var queueNames []string
+		for _, spec := range queue.OwnedQueues() {
+			queueNames = append(queueNames, spec.Name)
+		}
+		m.RegisterQueueLag(reporter, queueNames)
+	}
+}
+
+// newMetricsServer returns the admin server of /metrics, on METRICS_PORT.
+func newMetricsServer(m *metrics.Metrics) *http.Server {
+	r := chi.NewRouter()
+	r.Method(http.MethodGet, "/metrics", m.Handler())
+
+	return &http.Server{
+		Addr:         ":" + config.CONFIG.MetricsPort,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 30 * time.Second,
+		Handler:      r,
+	}
+}
+
+// startMetricsServer serves /metrics on the admin port until Shutdown.
+func startMetricsServer(server *http.Server, errCh chan<- error) {
+	go func() {
+		logrus.WithField("port", config.CONFIG.MetricsPort).Info("Metrics server listening")
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			errCh <- err
+		}
+	}()
+}
@@ -0,0 +1,16 @@
+-- Refresh tokens are stored server-side so they can be rotated, revoked and checked for reuse.
+-- Tokens issued from the same login share a family_id; reusing a rotated token revokes the whole family.
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id uuid,
+    user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    family_id uuid NOT NULL,
+    token_hash varchar(64) NOT NULL,
+    expires_at timestamp NOT NULL,
+    revoked_at timestamp,
+    replaced_by uuid,
+    created_at timestamp DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS refresh_tokens_family_id_idx ON refresh_tokens (family_id);
+CREATE INDEX IF NOT EXISTS refresh_tokens_user_id_idx ON refresh_tokens (user_id);
@@ -0,0 +1,16 @@
+-- Events are written here in the same transaction as the domain change and published to the broker by the outbox relay.
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id uuid DEFAULT uuid_generate_v4(),
+    exchange varchar(255) NOT NULL,
+    routing_key varchar(255) NOT NULL,
+    content_type varchar(255) NOT NULL DEFAULT 'application/json',
+    payload bytea NOT NULL,
+    attempts int NOT NULL DEFAULT 0,
+    last_error text,
+    next_attempt_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at timestamp DEFAULT CURRENT_TIMESTAMP,
+    sent_at timestamp,
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS outbox_events_pending_idx ON outbox_events (next_attempt_at) WHERE sent_at IS NULL;
@@ -0,0 +1,5 @@
+-- Envelope of the published message. The event id is used as the message id, so it stays the same across publish retries.
+ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS message_type varchar(255);
+ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS correlation_id varchar(255);
+ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS causation_id varchar(255);
+ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS headers jsonb;
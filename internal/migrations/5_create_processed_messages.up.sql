@@ -0,0 +1,10 @@
+-- Messages already handled by a consumer, used to ack duplicate deliveries without handling them again.
+-- Rows are written in the transaction of the handler and purged after a retention period.
+CREATE TABLE IF NOT EXISTS processed_messages (
+    consumer varchar(255) NOT NULL,
+    message_id varchar(255) NOT NULL,
+    processed_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (consumer, message_id)
+);
+
+CREATE INDEX IF NOT EXISTS processed_messages_processed_at_idx ON processed_messages (processed_at);
@@ -0,0 +1,6 @@
+-- The relay compares these columns with CURRENT_TIMESTAMP and the app reads them back as instants, so they must not
+-- depend on the session timezone. Existing values were written in the session timezone, which is how they are converted.
+ALTER TABLE outbox_events
+    ALTER COLUMN next_attempt_at TYPE timestamptz,
+    ALTER COLUMN created_at TYPE timestamptz,
+    ALTER COLUMN sent_at TYPE timestamptz;
@@ -0,0 +1,2 @@
+-- The purge compares processed_at with CURRENT_TIMESTAMP, it must not depend on the session timezone.
+ALTER TABLE processed_messages ALTER COLUMN processed_at TYPE timestamptz;
@@ -7,7 +7,12 @@ import (
 )
 
 // NullString is a helper wrapper around sql.NullString that handles the null case of the value when marshalling to JSON.
-type NullString sql.NullString
+// Set tells an explicit JSON null (Set, not Valid) from a missing field (not Set), ex: for PATCH bodies.
+type NullString struct {
+	String string
+	Valid  bool
+	Set    bool
+}
 
 // Scan implements the sql.Scanner interface so database/sql (and sqlx) can scan NULLs and strings into this type.
 func (ns *NullString) Scan(value any) error {
@@ -15,17 +20,16 @@ func (ns *NullString) Scan(value any) error {
 	if err := s.Scan(value); err != nil {
 		return err
 	}
-	*ns = NullString(s)
+	*ns = NullString{String: s.String, Valid: s.Valid}
 	return nil
 }
 
 // Value implements the driver.Valuer interface so this type can be used in query parameters.
 func (ns NullString) Value() (driver.Value, error) {
-	s := sql.NullString(ns)
-	if !s.Valid {
+	if !ns.Valid {
 		return nil, nil
 	}
-	return s.String, nil
+	return ns.String, nil
 }
 
 func (ns NullString) MarshalJSON() ([]byte, error) {
@@ -35,22 +39,27 @@ func (ns NullString) MarshalJSON() ([]byte, error) {
 	return json.Marshal(nil)
 }
 
+// UnmarshalJSON is only called for fields present in the JSON, null included, so it marks the value as Set.
 func (ns *NullString) UnmarshalJSON(b []byte) error {
 	var s *string
 	if err := json.Unmarshal(b, &s); err != nil {
 		return err
 	}
+	*ns = NullString{Set: true}
 	if s != nil {
 		ns.Valid = true
 		ns.String = *s
-	} else {
-		ns.Valid = false
 	}
 	return nil
 }
 
 // NullInt64 is a helper wrapper around sql.NullInt64 that handles the null case of the value when marshalling to JSON.
-type NullInt64 sql.NullInt64
+// Set has the same meaning as for NullString.
+type NullInt64 struct {
+	Int64 int64
+	Valid bool
+	Set   bool
+}
 
 // Scan implements the sql.Scanner interface so database/sql (and sqlx) can scan NULLs and integers into this type.
 func (ni *NullInt64) Scan(value any) error {
@@ -58,17 +67,16 @@ func (ni *NullInt64) Scan(value any) error {
 	if err := i.Scan(value); err != nil {
 		return err
 	}
-	*ni = NullInt64(i)
+	*ni = NullInt64{Int64: i.Int64, Valid: i.Valid}
 	return nil
 }
 
 // Value implements the driver.Valuer interface so this type can be used in query parameters.
 func (ni NullInt64) Value() (driver.Value, error) {
-	i := sql.NullInt64(ni)
-	if !i.Valid {
+	if !ni.Valid {
 		return nil, nil
 	}
-	return i.Int64, nil
+	return ni.Int64, nil
 }
 
 func (ni NullInt64) MarshalJSON() ([]byte, error) {
@@ -78,16 +86,16 @@ func (ni NullInt64) MarshalJSON() ([]byte, error) {
 	return json.Marshal(nil)
 }
 
+// UnmarshalJSON marks the value as Set, see NullString.UnmarshalJSON.
 func (ni *NullInt64) UnmarshalJSON(b []byte) error {
 	var s *int64
 	if err := json.Unmarshal(b, &s); err != nil {
 		return err
 	}
+	*ni = NullInt64{Set: true}
 	if s != nil {
 		ni.Valid = true
 		ni.Int64 = *s
-	} else {
-		ni.Valid = false
 	}
 	return nil
 }
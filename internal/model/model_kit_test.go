@@ -0,0 +1,79 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestNullStringUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want NullString
+	}{
+		{name: "absent", body: `{}`, want: NullString{}},
+		{name: "null", body: `{"value":null}`, want: NullString{Set: true}},
+		{name: "empty", body: `{"value":""}`, want: NullString{Set: true, Valid: true}},
+		{name: "value", body: `{"value":"Ada"}`, want: NullString{String: "Ada", Set: true, Valid: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got struct {
+				Value NullString `json:"value"`
+			}
+			if err := json.Unmarshal([]byte(tt.body), &got); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			if got.Value != tt.want {
+				t.Fatalf("got %+v, want %+v", got.Value, tt.want)
+			}
+		})
+	}
+}
+
+func TestNullInt64UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want NullInt64
+	}{
+		{name: "absent", body: `{}`, want: NullInt64{}},
+		{name: "null", body: `{"value":null}`, want: NullInt64{Set: true}},
+		{name: "zero", body: `{"value":0}`, want: NullInt64{Set: true, Valid: true}},
+		{name: "value", body: `{"value":42}`, want: NullInt64{Int64: 42, Set: true, Valid: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got struct {
+				Value NullInt64 `json:"value"`
+			}
+			if err := json.Unmarshal([]byte(tt.body), &got); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			if got.Value != tt.want {
+				t.Fatalf("got %+v, want %+v", got.Value, tt.want)
+			}
+		})
+	}
+}
+
+// A NULL column scans as not Set, so a scanned value is never mistaken for a PATCH field.
+func TestNullStringScanAndValue(t *testing.T) {
+	var ns NullString
+	if err := ns.Scan(nil); err != nil {
+		t.Fatalf("scan nil: %v", err)
+	}
+	if ns != (NullString{}) {
+		t.Fatalf("got %+v, want the zero value", ns)
+	}
+	if value, _ := ns.Value(); value != nil {
+		t.Fatalf("got %v, want nil", value)
+	}
+
+	if err := ns.Scan("Ada"); err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	if value, _ := ns.Value(); value != "Ada" {
+		t.Fatalf("got %v, want Ada", value)
+	}
+}
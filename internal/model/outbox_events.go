@@ -0,0 +1,30 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+type OutboxEvent struct {
+	ID            string     `db:"id"`
+	Exchange      string     `db:"exchange"`
+	RoutingKey    string     `db:"routing_key"`
+	ContentType   string     `db:"content_type"`
+	Payload       []byte     `db:"payload"`
+	MessageType   NullString `db:"message_type"`
+	CorrelationID NullString `db:"correlation_id"`
+	CausationID   NullString `db:"causation_id"`
+	// Headers is a JSON object, nil when the message has no headers.
+	Headers       []byte       `db:"headers"`
+	Attempts      int          `db:"attempts"`
+	LastError     NullString   `db:"last_error"`
+	NextAttemptAt time.Time    `db:"next_attempt_at"`
+	CreatedAt     time.Time    `db:"created_at"`
+	SentAt        sql.NullTime `db:"sent_at"`
+}
+
+// OutboxStats describe how far behind the outbox relay is.
+type OutboxStats struct {
+	Pending         int          `db:"pending"`
+	OldestPendingAt sql.NullTime `db:"oldest_pending_at"`
+}
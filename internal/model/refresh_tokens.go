@@ -0,0 +1,17 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+type RefreshToken struct {
+	ID         string       `db:"id"`
+	UserID     string       `db:"user_id"`
+	FamilyID   string       `db:"family_id"`
+	TokenHash  string       `db:"token_hash"`
+	ExpiresAt  time.Time    `db:"expires_at"`
+	RevokedAt  sql.NullTime `db:"revoked_at"`
+	ReplacedBy NullString   `db:"replaced_by"`
+	CreatedAt  time.Time    `db:"created_at"`
+}
@@ -0,0 +1,42 @@
+package model
+
+import (
+	"go-api-template/internal/libs/events"
+	"go-api-template/internal/libs/queue"
+)
+
+// Events published when users change. Bump the version on breaking changes and register
+// an upcaster from the previous version, see events.Register.
+
+type UserCreatedEvent struct {
+	ID        string `json:"id"`
+	Email     string `json:"email"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+}
+
+func (UserCreatedEvent) EventType() string { return string(queue.UsersCreatedRoutingKey) }
+func (UserCreatedEvent) EventVersion() int { return 1 }
+
+type UserUpdatedEvent struct {
+	ID        string `json:"id"`
+	Email     string `json:"email"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+}
+
+func (UserUpdatedEvent) EventType() string { return string(queue.UsersUpdatedRoutingKey) }
+func (UserUpdatedEvent) EventVersion() int { return 1 }
+
+type UserDeletedEvent struct {
+	ID string `json:"id"`
+}
+
+func (UserDeletedEvent) EventType() string { return string(queue.UsersDeletedRoutingKey) }
+func (UserDeletedEvent) EventVersion() int { return 1 }
+
+func init() {
+	events.Register[UserCreatedEvent](nil)
+	events.Register[UserUpdatedEvent](nil)
+	events.Register[UserDeletedEvent](nil)
+}
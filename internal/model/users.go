@@ -4,11 +4,13 @@ import (
 	"time"
 )
 
+// UserEmailUniqueConstraint is the name of the unique constraint on users.email.
+const UserEmailUniqueConstraint = "email_unique"
 
 type User struct {
 	ID                     string     `json:"id,omitempty" db:"id"`
-	FirstName              string     `json:"firstName" db:"first_name"`
-	LastName               string     `json:"lastName" db:"last_name"`
+	FirstName              NullString `json:"firstName" db:"first_name"`
+	LastName               NullString `json:"lastName" db:"last_name"`
 	Email                  string     `json:"email" db:"email"`
 	Password               string     `json:"-" db:"password"`
 	CreatedAt              time.Time  `json:"-" db:"created_at"`
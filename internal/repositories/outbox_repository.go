@@ -0,0 +1,100 @@
+package repositories
+
+import (
+	"context"
+	errs "go-api-template/internal/errors"
+	"go-api-template/internal/libs/database"
+	"go-api-template/internal/model"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type OutboxRepository struct {
+	db database.Querier
+}
+
+func NewOutboxRepository(db database.Querier) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// AddEvent should be called with the transaction of the domain change the event describes.
+// The event ID is the published message ID, it is generated when empty.
+func (r *OutboxRepository) AddEvent(ctx context.Context, event model.OutboxEvent) error {
+	if event.ID == "" {
+		event.ID = uuid.NewString()
+	}
+
+	_, err := r.db.NamedExecContext(ctx, `
+		INSERT INTO outbox_events (id, exchange, routing_key, content_type, payload, message_type, correlation_id, causation_id, headers)
+		VALUES (:id, :exchange, :routing_key, :content_type, :payload, :message_type, :correlation_id, :causation_id, :headers)`, event)
+	return errs.FromDatabaseError(err, "outbox event")
+}
+
+// ClaimPendingEvents returns the events that are due, oldest first, and pushes their next attempt
+// claimFor later so other relays skip them while they are published. Rows locked by other relays
+// are skipped. An event that is neither marked sent nor failed is due again once the claim expired.
+func (r *OutboxRepository) ClaimPendingEvents(ctx context.Context, limit int, claimFor time.Duration) ([]model.OutboxEvent, error) {
+	events := []model.OutboxEvent{}
+	err := r.db.SelectContext(ctx, &events, `
+		WITH due AS (
+			SELECT id FROM outbox_events
+			WHERE sent_at IS NULL AND next_attempt_at <= CURRENT_TIMESTAMP
+			ORDER BY created_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE outbox_events SET next_attempt_at = CURRENT_TIMESTAMP + $2::interval
+		FROM due
+		WHERE outbox_events.id = due.id
+		RETURNING outbox_events.*`, limit, database.Interval(claimFor))
+	if err != nil {
+		return nil, errs.FromDatabaseError(err, "outbox event")
+	}
+	slices.SortFunc(events, func(a, b model.OutboxEvent) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return events, nil
+}
+
+func (r *OutboxRepository) MarkEventsSent(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := r.db.ExecContext(ctx, "UPDATE outbox_events SET sent_at = CURRENT_TIMESTAMP WHERE id = ANY($1)", pq.Array(ids))
+	return errs.FromDatabaseError(err, "outbox event")
+}
+
+// MarkEventFailed records the failed attempt, the event is due again retryIn later.
+func (r *OutboxRepository) MarkEventFailed(ctx context.Context, id string, lastError string, retryIn time.Duration) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE outbox_events
+		SET attempts = attempts + 1, last_error = $2, next_attempt_at = CURRENT_TIMESTAMP + $3::interval
+		WHERE id = $1`, id, lastError, database.Interval(retryIn))
+	return errs.FromDatabaseError(err, "outbox event")
+}
+
+// DeleteSentEvents deletes the events sent more than retention ago.
+func (r *OutboxRepository) DeleteSentEvents(ctx context.Context, retention time.Duration) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM outbox_events
+		WHERE sent_at IS NOT NULL AND sent_at < CURRENT_TIMESTAMP - $1::interval`, database.Interval(retention))
+	if err != nil {
+		return 0, errs.FromDatabaseError(err, "outbox event")
+	}
+	return result.RowsAffected()
+}
+
+func (r *OutboxRepository) GetStats(ctx context.Context) (model.OutboxStats, error) {
+	var stats model.OutboxStats
+	err := r.db.GetContext(ctx, &stats, `
+		SELECT COUNT(*) AS pending, MIN(created_at) AS oldest_pending_at
+		FROM outbox_events
+		WHERE sent_at IS NULL`)
+	if err != nil {
+		return model.OutboxStats{}, errs.FromDatabaseError(err, "outbox event")
+	}
+	return stats, nil
+}
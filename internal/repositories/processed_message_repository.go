@@ -0,0 +1,45 @@
+package repositories
+
+import (
+	"context"
+	errs "go-api-template/internal/errors"
+	"go-api-template/internal/libs/database"
+	"time"
+)
+
+type ProcessedMessageRepository struct {
+	db database.Querier
+}
+
+func NewProcessedMessageRepository(db database.Querier) *ProcessedMessageRepository {
+	return &ProcessedMessageRepository{db: db}
+}
+
+// MarkProcessed records the message for the consumer and reports false if it was already recorded.
+// In a transaction, a concurrent insert of the same message waits for it to commit or roll back.
+func (r *ProcessedMessageRepository) MarkProcessed(ctx context.Context, consumer string, messageID string) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO processed_messages (consumer, message_id)
+		VALUES ($1, $2)
+		ON CONFLICT (consumer, message_id) DO NOTHING`, consumer, messageID)
+	if err != nil {
+		return false, errs.FromDatabaseError(err, "processed message")
+	}
+
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return inserted == 1, nil
+}
+
+// DeleteProcessedMessages deletes the messages processed more than retention ago.
+func (r *ProcessedMessageRepository) DeleteProcessedMessages(ctx context.Context, retention time.Duration) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM processed_messages
+		WHERE processed_at < CURRENT_TIMESTAMP - $1::interval`, database.Interval(retention))
+	if err != nil {
+		return 0, errs.FromDatabaseError(err, "processed message")
+	}
+	return result.RowsAffected()
+}
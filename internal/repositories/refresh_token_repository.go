@@ -0,0 +1,57 @@
+package repositories
+
+import (
+	"context"
+	errs "go-api-template/internal/errors"
+	"go-api-template/internal/libs/database"
+	"go-api-template/internal/model"
+)
+
+type RefreshTokenRepository struct {
+	db database.Querier
+}
+
+func NewRefreshTokenRepository(db database.Querier) *RefreshTokenRepository {
+	return &RefreshTokenRepository{db: db}
+}
+
+func (r *RefreshTokenRepository) GetRefreshToken(ctx context.Context, id string) (model.RefreshToken, error) {
+	var token model.RefreshToken
+	err := r.db.GetContext(ctx, &token, "SELECT * FROM refresh_tokens WHERE id = $1", id)
+	if err != nil {
+		return model.RefreshToken{}, errs.FromDatabaseError(err, "refresh token")
+	}
+	return token, nil
+}
+
+func (r *RefreshTokenRepository) CreateRefreshToken(ctx context.Context, token model.RefreshToken) error {
+	_, err := r.db.NamedExecContext(ctx, `
+		INSERT INTO refresh_tokens (id, user_id, family_id, token_hash, expires_at)
+		VALUES (:id, :user_id, :family_id, :token_hash, :expires_at)`, token)
+	return errs.FromDatabaseError(err, "refresh token")
+}
+
+// RotateRefreshToken revokes a token and points it to its replacement.
+// It returns false when the token was already revoked, which means it is being reused.
+func (r *RefreshTokenRepository) RotateRefreshToken(ctx context.Context, id string, replacedBy string) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE refresh_tokens
+		SET revoked_at = CURRENT_TIMESTAMP, replaced_by = $2
+		WHERE id = $1 AND revoked_at IS NULL`, id, replacedBy)
+	if err != nil {
+		return false, errs.FromDatabaseError(err, "refresh token")
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+func (r *RefreshTokenRepository) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE refresh_tokens
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE family_id = $1 AND revoked_at IS NULL`, familyID)
+	return errs.FromDatabaseError(err, "refresh token")
+}
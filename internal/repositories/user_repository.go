@@ -2,16 +2,20 @@ package repositories
 
 import (
 	"context"
+	"database/sql"
+	errs "go-api-template/internal/errors"
+	"go-api-template/internal/libs/database"
+	"go-api-template/internal/libs/pagination"
 	"go-api-template/internal/model"
 
 	"github.com/jmoiron/sqlx"
 )
 
 type UserRepository struct {
-	db *sqlx.DB
+	db database.Querier
 }
 
-func NewUserRepository(db *sqlx.DB) *UserRepository {
+func NewUserRepository(db database.Querier) *UserRepository {
 	return &UserRepository{db: db}
 }
 
@@ -19,7 +23,7 @@ func (r *UserRepository) GetUser(ctx context.Context, id string) (model.User, er
 	var user model.User
 	err := r.db.GetContext(ctx, &user, "SELECT * FROM users WHERE id = $1", id)
 	if err != nil {
-		return model.User{}, err
+		return model.User{}, errs.FromDatabaseError(err, "user")
 	}
 	return user, nil
 }
@@ -36,7 +40,7 @@ func (r *UserRepository) CreateUser(ctx context.Context, user model.User) (model
 
 	var created model.User
 	if err := r.db.GetContext(ctx, &created, query, args...); err != nil {
-		return model.User{}, err
+		return model.User{}, errs.FromDatabaseError(err, "user")
 	}
 	return created, nil
 }
@@ -45,7 +49,107 @@ func (r *UserRepository) CheckIfUserEmailExists(ctx context.Context, email strin
 	var count int
 	err := r.db.GetContext(ctx, &count, "SELECT COUNT(*) FROM users WHERE email = $1", email)
 	if err != nil {
-		return false, err
+		return false, errs.FromDatabaseError(err, "user")
+	}
+	return count > 0, nil
+}
+
+func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (model.User, error) {
+	var user model.User
+	err := r.db.GetContext(ctx, &user, "SELECT * FROM users WHERE email = $1", email)
+	if err != nil {
+		return model.User{}, errs.FromDatabaseError(err, "user")
+	}
+	return user, nil
+}
+
+func (r *UserRepository) ListUsers(ctx context.Context, params pagination.Params) ([]model.User, pagination.Page, error) {
+	fragment := params.SQL(1)
+
+	query := "SELECT * FROM users"
+	if fragment.Where != "" {
+		query += " WHERE " + fragment.Where
+	}
+	query += " ORDER BY " + fragment.OrderBy + " " + fragment.LimitOffset
+
+	users := []model.User{}
+	if err := r.db.SelectContext(ctx, &users, query, fragment.Args...); err != nil {
+		return nil, pagination.Page{}, errs.FromDatabaseError(err, "user")
+	}
+
+	return pagination.Paginate(users, params, userColumnValue)
+}
+
+func (r *UserRepository) CountUsers(ctx context.Context) (int, error) {
+	var count int
+	err := r.db.GetContext(ctx, &count, "SELECT COUNT(*) FROM users")
+	if err != nil {
+		return 0, errs.FromDatabaseError(err, "user")
+	}
+	return count, nil
+}
+
+func (r *UserRepository) UpdateUser(ctx context.Context, user model.User) (model.User, error) {
+	query, args, err := sqlx.Named(`
+		UPDATE users
+		SET first_name = :first_name, last_name = :last_name, email = :email, updated_at = CURRENT_TIMESTAMP
+		WHERE id = :id
+		RETURNING *`, user)
+	if err != nil {
+		return model.User{}, err
+	}
+	query = r.db.Rebind(query)
+
+	var updated model.User
+	if err := r.db.GetContext(ctx, &updated, query, args...); err != nil {
+		return model.User{}, errs.FromDatabaseError(err, "user")
+	}
+	return updated, nil
+}
+
+// DeleteUser returns a not found error when there is no user with the given id.
+func (r *UserRepository) DeleteUser(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM users WHERE id = $1", id)
+	if err != nil {
+		return errs.FromDatabaseError(err, "user")
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return errs.FromDatabaseError(sql.ErrNoRows, "user")
+	}
+	return nil
+}
+
+func (r *UserRepository) CheckIfOtherUserEmailExists(ctx context.Context, id string, email string) (bool, error) {
+	var count int
+	err := r.db.GetContext(ctx, &count, "SELECT COUNT(*) FROM users WHERE email = $1 AND id <> $2", email, id)
+	if err != nil {
+		return false, errs.FromDatabaseError(err, "user")
 	}
 	return count > 0, nil
 }
+
+// Sort columns of the nullable names, a NULL name sorts like an empty one.
+const (
+	UserFirstNameSortColumn = "COALESCE(first_name, '')"
+	UserLastNameSortColumn  = "COALESCE(last_name, '')"
+)
+
+// userColumnValue returns the value of a sortable column, used to build pagination cursors.
+func userColumnValue(user model.User, column string) any {
+	switch column {
+	case UserFirstNameSortColumn:
+		return user.FirstName.String
+	case UserLastNameSortColumn:
+		return user.LastName.String
+	case "email":
+		return user.Email
+	case "created_at":
+		return user.CreatedAt
+	default:
+		return user.ID
+	}
+}
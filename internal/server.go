@@ -6,10 +6,13 @@ import (
 	"fmt"
 	"go-api-template/config"
 	"go-api-template/internal/libs/database"
+	"go-api-template/internal/libs/health"
+	"go-api-template/internal/libs/metrics"
 	"go-api-template/internal/libs/queue"
 	"go-api-template/internal/libs/renderer"
 	"go-api-template/internal/service"
 	httpTransport "go-api-template/internal/transport/http"
+	"go-api-template/internal/transport/http/middlewares"
 	queueTransport "go-api-template/internal/transport/queue"
 	"net/http"
 	"time"
@@ -26,11 +29,19 @@ type Server struct {
 	Queue            *queueTransport.QueueTransport
 	ResponseRenderer *renderer.ResponseRenderer
 
-	Services *service.Services
+	Services    *service.Services
+	OutboxRelay *service.OutboxRelay
+
+	// Health runs the readiness checks, it is draining once shutdown began.
+	Health *health.Health
 
 	// Used for migrations and connection closing on shutdown
 	PostgresDB *database.PostgresDB
-	RabbitMQ   *queue.RabbitMQ
+	Broker     queue.Broker
+	// RabbitMQ is set when it is the broker.
+	RabbitMQ *queue.RabbitMQ
+	// Metrics is set when METRICS_ENABLED, it is the registry served on /metrics.
+	Metrics *metrics.Metrics
 }
 
 func NewServer() *Server {
@@ -39,47 +50,118 @@ func NewServer() *Server {
 		panic(err)
 	}
 
-	var rabbit *queue.RabbitMQ
+	broker, rabbit, err := newBroker()
+	if err != nil {
+		panic(err)
+	}
+
 	var publisher queue.Publisher = queue.NoopPublisher{}
-	if config.CONFIG.RabbitMQEnabled {
-		rabbit = queue.NewRabbitMQ(config.CONFIG.RabbitMQURL, queue.RabbitMQOptions{
-			Prefetch: config.CONFIG.RabbitMQPrefetch,
-		})
-		if err := rabbit.Connect(); err != nil {
-			panic(err)
-		}
-		if err := rabbit.EnsureTopology(queue.EventsExchangeName, queue.OwnedQueues()); err != nil {
-			panic(err)
-		}
-		publisher = rabbit
+	if broker != nil {
+		publisher = broker
 	}
 
-	services := service.NewServices(postgresDB.Database, publisher)
+	services := service.NewServices(postgresDB.Database)
+	outboxRelay := service.NewOutboxRelay(postgresDB.Database, publisher, service.OutboxRelayOptions{
+		PollInterval: config.CONFIG.OutboxPollInterval,
+		BatchSize:    config.CONFIG.OutboxBatchSize,
+		Retention:    config.CONFIG.OutboxRetention,
+	})
 
 	responseRenderer := renderer.NewResponseRenderer()
 
 	httpTransport := httpTransport.NewHTTPTransport(services, responseRenderer)
-	queueTransport := queueTransport.NewQueueTransport(services, rabbit)
+	var appMetrics *metrics.Metrics
+	var handlerObservers []queueTransport.HandlerObserver
+	if config.CONFIG.MetricsEnabled {
+		appMetrics = metrics.New()
+		handlerObservers = append(handlerObservers, appMetrics.QueueHandlerObserver())
+	}
+	queueTransport := queueTransport.NewQueueTransport(services, broker, handlerObservers...)
 
-	return &Server{
+	if broker != nil {
+		// The bindings come from the patterns the queue handlers are registered for.
+		if err := broker.EnsureTopology(queue.EventsExchangeName, queueTransport.Topology()); err != nil {
+			panic(err)
+		}
+	}
+
+	server := &Server{
 		Services:         services,
+		OutboxRelay:      outboxRelay,
 		HTTP:             httpTransport,
 		Queue:            queueTransport,
 		ResponseRenderer: responseRenderer,
 		PostgresDB:       postgresDB,
+		Broker:           broker,
 		RabbitMQ:         rabbit,
+		Metrics:          appMetrics,
+	}
+	server.Health = server.newHealth()
+	if config.CONFIG.MetricsEnabled {
+		server.registerMetrics()
+	}
+	return server
+}
+
+// newBroker connects the broker selected by QUEUE_BACKEND, it returns a nil broker when there is none.
+// The RabbitMQ client is also returned as such for its RabbitMQ-only features.
+func newBroker() (queue.Broker, *queue.RabbitMQ, error) {
+	switch config.CONFIG.QueueBackend {
+	case config.QueueBackendRabbitMQ:
+		rabbit := queue.NewRabbitMQ(config.CONFIG.RabbitMQURL, queue.RabbitMQOptions{
+			Prefetch:       config.CONFIG.RabbitMQPrefetch,
+			ConfirmPublish: config.CONFIG.RabbitMQPublishConfirm,
+			Mandatory:      config.CONFIG.RabbitMQPublishMandatory,
+			PublishTimeout: config.CONFIG.RabbitMQPublishTimeout,
+
+			ReconnectMinDelay: config.CONFIG.RabbitMQReconnectMinDelay,
+			ReconnectMaxDelay: config.CONFIG.RabbitMQReconnectMaxDelay,
+			ShutdownTimeout:   config.CONFIG.RabbitMQShutdownTimeout,
+
+			DirectReplyTo:  config.CONFIG.RabbitMQDirectReplyTo,
+			RequestTimeout: config.CONFIG.RabbitMQRequestTimeout,
+		})
+		if err := rabbit.Connect(); err != nil {
+			return nil, nil, err
+		}
+		return rabbit, rabbit, nil
+
+	case config.QueueBackendNATS:
+		nats := queue.NewNATSJetStream(config.CONFIG.NatsURL, queue.NATSJetStreamOptions{
+			Prefetch: config.CONFIG.NatsPrefetch,
+			AckWait:  config.CONFIG.NatsAckWait,
+		})
+		if err := nats.Connect(); err != nil {
+			return nil, nil, err
+		}
+		return nats, nil, nil
+
+	case config.QueueBackendMemory:
+		// Single binary dev mode: events are published and consumed in-process.
+		return queue.NewInMemoryBroker(queue.InMemoryOptions{}), nil, nil
+
+	case config.QueueBackendNone:
+		return nil, nil, nil
+
+	default:
+		return nil, nil, fmt.Errorf("unknown QUEUE_BACKEND %q", config.CONFIG.QueueBackend)
 	}
 }
 
 func (s *Server) Run(ctx context.Context) error {
 	r := chi.NewRouter()
-	r.Use(middleware.Logger)
+	r.Use(middlewares.RequestIDMiddleware)
+	r.Use(middlewares.TracingMiddleware)
+	r.Use(middlewares.LoggerMiddleware)
+	if config.CONFIG.MetricsEnabled {
+		r.Use(middlewares.MetricsMiddleware(s.Metrics))
+	}
 	r.Use(middleware.Recoverer)
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   config.CONFIG.AllowedOrigins,
 		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-Request-ID", "traceparent", "tracestate", "baggage"},
+		ExposedHeaders:   []string{"Link", "X-Request-ID"},
 		AllowCredentials: false,
 		MaxAge:           300,
 	}))
@@ -92,6 +174,11 @@ func (s *Server) Run(ctx context.Context) error {
 	})
 	r.Use(secureMiddleware.Handler)
 
+	// Without an admin port, /metrics is served with the API. Keep it private at the proxy.
+	if config.CONFIG.MetricsEnabled && config.CONFIG.MetricsPort == "" {
+		r.Method(http.MethodGet, "/metrics", s.Metrics.Handler())
+	}
+
 	r.NotFound(s.NotFoundHandler)
 	r.MethodNotAllowed(s.NotAllowedHandler)
 
@@ -105,7 +192,18 @@ func (s *Server) Run(ctx context.Context) error {
 		Handler:      r,
 	}
 
-	errCh := make(chan error, 1)
+	// Cancelled on the first error too, so the outbox relay and the consumers stop.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// One slot per sender (HTTP, metrics, outbox relay and consumers), none blocks once Run returned.
+	errCh := make(chan error, 4)
+
+	var metricsServer *http.Server
+	if config.CONFIG.MetricsEnabled && config.CONFIG.MetricsPort != "" {
+		metricsServer = newMetricsServer(s.Metrics)
+		startMetricsServer(metricsServer, errCh)
+	}
 
 	// Start HTTP server
 	go func() {
@@ -119,39 +217,71 @@ func (s *Server) Run(ctx context.Context) error {
 
 	}()
 
-	// Start RabbitMQ listener
-	if config.CONFIG.RabbitMQEnabled && s.Queue != nil {
+	// Start outbox relay, it publishes the events written by the services
+	relayDone := make(chan struct{})
+	go func() {
+		defer close(relayDone)
+		if err := s.OutboxRelay.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			errCh <- err
+		}
+	}()
+
+	// Start queue consumers
+	consumersDone := make(chan struct{})
+	if s.Queue != nil {
 		go func() {
+			defer close(consumersDone)
 			if err := s.Queue.StartConsumers(ctx); err != nil && !errors.Is(err, context.Canceled) {
 				errCh <- err
 			}
 		}()
+	} else {
+		close(consumersDone)
 	}
 
+	var runErr error
 	select {
 	case <-ctx.Done():
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-
-		server.Shutdown(shutdownCtx)
-
-		s.OnShutdown()
-		return nil
+		// Fail readiness first, the load balancer stops routing to this instance while it still serves.
+		s.Health.SetDraining()
+		if config.CONFIG.ShutdownDrainDelay > 0 {
+			logrus.WithField("delay", config.CONFIG.ShutdownDrainDelay).Info("Draining before shutdown")
+			time.Sleep(config.CONFIG.ShutdownDrainDelay)
+		}
 
 	case err := <-errCh:
-		if errors.Is(err, http.ErrServerClosed) {
-			s.OnShutdown()
-			return nil
+		s.Health.SetDraining()
+		if !errors.Is(err, http.ErrServerClosed) {
+			logrus.WithError(err).Error("Server stopped with error")
+			runErr = err
 		}
-		logrus.WithError(err).Error("Server stopped with error")
-		s.OnShutdown()
-		return err
 	}
+
+	// The same ordered shutdown on a signal and on an error.
+	cancel()
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelShutdown()
+
+	server.Shutdown(shutdownCtx)
+	if metricsServer != nil {
+		metricsServer.Shutdown(shutdownCtx)
+	}
+
+	// Consumers finish their in-flight messages and the relay records its last batch before the connections are closed.
+	<-consumersDone
+	<-relayDone
+
+	s.OnShutdown()
+	return runErr
 }
 
 func (s *Server) RegisterRoutes(r chi.Router) {
 	// All top level routes should be registered here.
+	r.Get("/healthz", s.HealthzHandler)
+	r.Get("/readyz", s.ReadyzHandler)
+
 	r.Route("/api", func(r chi.Router) {
+		r.Route("/auth", s.HTTP.Auth.RegisterRoutes)
 		r.Route("/users", s.HTTP.Users.RegisterRoutes)
 	})
 }
@@ -159,12 +289,16 @@ func (s *Server) RegisterRoutes(r chi.Router) {
 // OnShutdown is called when the server has a panic.
 // It is used to cleanup the server resources.
 func (s *Server) OnShutdown() {
+	if s.Health != nil {
+		s.Health.SetDraining()
+	}
+
 	s.PostgresDB.Close()
 	logrus.Info("PostgresDB closed")
 
-	if s.RabbitMQ != nil {
-		_ = s.RabbitMQ.Close()
-		logrus.Info("RabbitMQ closed")
+	if s.Broker != nil {
+		_ = s.Broker.Close()
+		logrus.WithField("backend", config.CONFIG.QueueBackend).Info("Queue broker closed")
 	}
 
 	logrus.WithError(fmt.Errorf("Executed OnShutdown")).Error("Executed OnShutdown")
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	errs "go-api-template/internal/errors"
+	"go-api-template/internal/libs/auth"
+	"go-api-template/internal/libs/crypto"
+	"go-api-template/internal/libs/database"
+	"go-api-template/internal/libs/logger"
+	"go-api-template/internal/model"
+	"go-api-template/internal/repositories"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// errRefreshTokenRotated rolls back a rotation that lost the race for the old token.
+var errRefreshTokenRotated = errors.New("refresh token already rotated")
+
+type AuthService struct {
+	txRunner               *database.TxRunner
+	UserRepository         *repositories.UserRepository
+	RefreshTokenRepository *repositories.RefreshTokenRepository
+}
+
+func NewAuthService(txRunner *database.TxRunner, userRepository *repositories.UserRepository, refreshTokenRepository *repositories.RefreshTokenRepository) *AuthService {
+	return &AuthService{txRunner: txRunner, UserRepository: userRepository, RefreshTokenRepository: refreshTokenRepository}
+}
+
+// Login checks the credentials and starts a new refresh token family.
+func (s *AuthService) Login(ctx context.Context, input LoginInput) (TokenPair, error) {
+	user, err := s.UserRepository.GetUserByEmail(ctx, input.Email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return TokenPair{}, errs.NewInvalidCredentialsError()
+		}
+		return TokenPair{}, err
+	}
+
+	if !crypto.VerifyPassword(input.Password, user.Password) {
+		return TokenPair{}, errs.NewInvalidCredentialsError()
+	}
+
+	return s.issueTokenPair(ctx, s.RefreshTokenRepository, user.ID, user.Email, uuid.NewString())
+}
+
+// Refresh rotates a refresh token. Presenting a token that was already rotated or revoked
+// is treated as theft and revokes every token of its family.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (TokenPair, error) {
+	stored, err := s.getStoredRefreshToken(ctx, refreshToken)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	if stored.RevokedAt.Valid {
+		if err := s.revokeFamily(ctx, stored); err != nil {
+			return TokenPair{}, err
+		}
+		return TokenPair{}, errs.NewRefreshTokenReusedError()
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		return TokenPair{}, errs.NewInvalidRefreshTokenError()
+	}
+
+	user, err := s.UserRepository.GetUser(ctx, stored.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return TokenPair{}, errs.NewInvalidRefreshTokenError()
+		}
+		return TokenPair{}, err
+	}
+
+	// The new token and the revocation of the old one commit together. The revocation only
+	// matches a token that is not revoked yet, so of two concurrent refreshes only one succeeds.
+	var pair TokenPair
+	err = s.txRunner.WithTx(ctx, func(ctx context.Context, tx database.Querier) error {
+		refreshTokenRepository := repositories.NewRefreshTokenRepository(tx)
+
+		pair, err = s.issueTokenPair(ctx, refreshTokenRepository, user.ID, user.Email, stored.FamilyID)
+		if err != nil {
+			return err
+		}
+
+		rotated, err := refreshTokenRepository.RotateRefreshToken(ctx, stored.ID, pair.refreshTokenID)
+		if err != nil {
+			return err
+		}
+		if !rotated {
+			return errRefreshTokenRotated
+		}
+		return nil
+	})
+	if errors.Is(err, errRefreshTokenRotated) {
+		// Another request rotated the same token concurrently.
+		if err := s.revokeFamily(ctx, stored); err != nil {
+			return TokenPair{}, err
+		}
+		return TokenPair{}, errs.NewRefreshTokenReusedError()
+	}
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	return pair, nil
+}
+
+// Logout revokes the refresh token family so none of the tokens of this login can be refreshed again.
+func (s *AuthService) Logout(ctx context.Context, refreshToken string) error {
+	stored, err := s.getStoredRefreshToken(ctx, refreshToken)
+	if err != nil {
+		return err
+	}
+	return s.RefreshTokenRepository.RevokeRefreshTokenFamily(ctx, stored.FamilyID)
+}
+
+func (s *AuthService) getStoredRefreshToken(ctx context.Context, refreshToken string) (model.RefreshToken, error) {
+	claims, err := auth.ParseRefreshToken(refreshToken)
+	if err != nil {
+		return model.RefreshToken{}, errs.NewInvalidRefreshTokenError()
+	}
+
+	stored, err := s.RefreshTokenRepository.GetRefreshToken(ctx, claims.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.RefreshToken{}, errs.NewInvalidRefreshTokenError()
+		}
+		return model.RefreshToken{}, err
+	}
+
+	if stored.UserID != claims.UserID() || stored.TokenHash != crypto.HashToken(refreshToken) {
+		return model.RefreshToken{}, errs.NewInvalidRefreshTokenError()
+	}
+
+	return stored, nil
+}
+
+func (s *AuthService) revokeFamily(ctx context.Context, token model.RefreshToken) error {
+	logger.FromContext(ctx).WithFields(logrus.Fields{
+		"userId":   token.UserID,
+		"familyId": token.FamilyID,
+	}).Warn("Refresh token reuse detected, revoking token family")
+
+	return s.RefreshTokenRepository.RevokeRefreshTokenFamily(ctx, token.FamilyID)
+}
+
+func (s *AuthService) issueTokenPair(ctx context.Context, refreshTokenRepository *repositories.RefreshTokenRepository, userID string, email string, familyID string) (TokenPair, error) {
+	accessToken, accessExpiresAt, err := auth.GenerateAccessToken(userID, email)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	refreshTokenID := uuid.NewString()
+	refreshToken, refreshExpiresAt, err := auth.GenerateRefreshToken(userID, refreshTokenID)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	err = refreshTokenRepository.CreateRefreshToken(ctx, model.RefreshToken{
+		ID:        refreshTokenID,
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: crypto.HashToken(refreshToken),
+		ExpiresAt: refreshExpiresAt.UTC(),
+	})
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	return TokenPair{
+		AccessToken:           accessToken,
+		AccessTokenExpiresAt:  accessExpiresAt.UTC(),
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: refreshExpiresAt.UTC(),
+		refreshTokenID:        refreshTokenID,
+	}, nil
+}
@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-api-template/internal/libs/database"
+	"go-api-template/internal/libs/queue"
+	"go-api-template/internal/model"
+	"go-api-template/internal/repositories"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+)
+
+const outboxMaxBackoff = 5 * time.Minute
+
+// outboxClaimTimeout is how long claimed events are hidden from other relays while they are published.
+const outboxClaimTimeout = time.Minute
+
+// outboxMarkTimeout bounds recording the outcome of a batch once the relay is stopping.
+const outboxMarkTimeout = 5 * time.Second
+
+type OutboxRelayOptions struct {
+	PollInterval time.Duration
+	BatchSize    int
+	// Retention is how long sent events are kept before they are purged.
+	Retention time.Duration
+}
+
+// OutboxRelayStats are the lag metrics of the relay.
+type OutboxRelayStats struct {
+	Pending   int
+	Lag       time.Duration
+	Published int64
+	Failed    int64
+}
+
+// OutboxRelay publishes the events written to the outbox_events table by the services.
+// Several relays can run at the same time, each batch is claimed with SKIP LOCKED.
+type OutboxRelay struct {
+	db        *sqlx.DB
+	txRunner  *database.TxRunner
+	publisher queue.Publisher
+	opts      OutboxRelayOptions
+
+	statsMu   sync.RWMutex
+	stats     model.OutboxStats
+	published atomic.Int64
+	failed    atomic.Int64
+
+	log *logrus.Entry
+}
+
+func NewOutboxRelay(db *sqlx.DB, publisher queue.Publisher, opts OutboxRelayOptions) *OutboxRelay {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Second
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	return &OutboxRelay{
+		db:        db,
+		txRunner:  database.NewTxRunner(db),
+		publisher: publisher,
+		opts:      opts,
+		log:       logrus.WithField("component", "outbox-relay"),
+	}
+}
+
+// Run polls the outbox until ctx is done.
+func (r *OutboxRelay) Run(ctx context.Context) error {
+	r.log.WithFields(logrus.Fields{
+		"pollInterval": r.opts.PollInterval,
+		"batchSize":    r.opts.BatchSize,
+	}).Info("Outbox relay started")
+
+	ticker := time.NewTicker(r.opts.PollInterval)
+	defer ticker.Stop()
+
+	lastPurge := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		// Keep draining while full batches come back.
+		for {
+			relayed, err := r.RelayPending(ctx)
+			if err != nil {
+				if !errors.Is(err, context.Canceled) {
+					r.log.WithError(err).Error("Outbox relay batch failed")
+				}
+				break
+			}
+			if relayed < r.opts.BatchSize {
+				break
+			}
+		}
+
+		r.refreshStats(ctx)
+
+		if r.opts.Retention > 0 && time.Since(lastPurge) > time.Hour {
+			lastPurge = time.Now()
+			r.purgeSent(ctx)
+		}
+	}
+}
+
+// RelayPending publishes one batch of due events and returns how many were handled.
+//
+// The batch is claimed in a short transaction and published outside of it: a transaction retried
+// after a serialization failure must not publish the events twice. The outcome is then recorded in
+// a second transaction. Events of a relay that dies in between are published again once their
+// claim expires, consumers deduplicate them by message ID.
+func (r *OutboxRelay) RelayPending(ctx context.Context) (int, error) {
+	var events []model.OutboxEvent
+	err := r.txRunner.WithTx(ctx, func(ctx context.Context, tx database.Querier) error {
+		var err error
+		events, err = repositories.NewOutboxRepository(tx).ClaimPendingEvents(ctx, r.opts.BatchSize, outboxClaimTimeout)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var sent []string
+	var failed []outboxFailure
+	for _, event := range events {
+		if ctx.Err() != nil {
+			// The remaining events are published again when their claim expires.
+			break
+		}
+		if err := r.publish(ctx, event); err != nil {
+			failure := outboxFailure{event: event, err: err, retryIn: outboxBackoff(event.Attempts)}
+			r.log.WithError(err).WithFields(logrus.Fields{
+				"eventId":       event.ID,
+				"routingKey":    event.RoutingKey,
+				"correlationId": event.CorrelationID.String,
+				"attempts":      event.Attempts + 1,
+				"retryIn":       failure.retryIn,
+			}).Warn("Outbox event publish failed")
+			failed = append(failed, failure)
+			continue
+		}
+		sent = append(sent, event.ID)
+	}
+
+	// Record what was published even when the relay is stopping, or it's published again.
+	markCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), outboxMarkTimeout)
+	defer cancel()
+	err = r.txRunner.WithTx(markCtx, func(ctx context.Context, tx database.Querier) error {
+		outbox := repositories.NewOutboxRepository(tx)
+		if err := outbox.MarkEventsSent(ctx, sent); err != nil {
+			return err
+		}
+		for _, failure := range failed {
+			if err := outbox.MarkEventFailed(ctx, failure.event.ID, failure.err.Error(), failure.retryIn); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	r.published.Add(int64(len(sent)))
+	r.failed.Add(int64(len(failed)))
+	return len(events), nil
+}
+
+type outboxFailure struct {
+	event   model.OutboxEvent
+	err     error
+	retryIn time.Duration
+}
+
+func (r *OutboxRelay) publish(ctx context.Context, event model.OutboxEvent) error {
+	var headers map[string]any
+	if len(event.Headers) > 0 {
+		if err := json.Unmarshal(event.Headers, &headers); err != nil {
+			r.log.WithError(err).WithField("eventId", event.ID).Warn("Outbox event has invalid headers, publishing without them")
+		}
+	}
+
+	return r.publisher.Publish(ctx, queue.Message{
+		Exchange:      event.Exchange,
+		RoutingKey:    event.RoutingKey,
+		Body:          event.Payload,
+		ContentType:   event.ContentType,
+		MessageID:     event.ID,
+		CorrelationID: event.CorrelationID.String,
+		CausationID:   event.CausationID.String,
+		Headers:       headers,
+		Timestamp:     event.CreatedAt,
+		Type:          event.MessageType.String,
+	})
+}
+
+// Stats returns the number of pending events, the age of the oldest one and the publish counters.
+func (r *OutboxRelay) Stats() OutboxRelayStats {
+	r.statsMu.RLock()
+	stats := r.stats
+	r.statsMu.RUnlock()
+
+	var lag time.Duration
+	if stats.OldestPendingAt.Valid {
+		lag = max(time.Since(stats.OldestPendingAt.Time), 0)
+	}
+
+	return OutboxRelayStats{
+		Pending:   stats.Pending,
+		Lag:       lag,
+		Published: r.published.Load(),
+		Failed:    r.failed.Load(),
+	}
+}
+
+func (r *OutboxRelay) refreshStats(ctx context.Context) {
+	stats, err := repositories.NewOutboxRepository(r.db).GetStats(ctx)
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			r.log.WithError(err).Warn("Can't read outbox stats")
+		}
+		return
+	}
+
+	r.statsMu.Lock()
+	r.stats = stats
+	r.statsMu.Unlock()
+}
+
+func (r *OutboxRelay) purgeSent(ctx context.Context) {
+	deleted, err := repositories.NewOutboxRepository(r.db).DeleteSentEvents(ctx, r.opts.Retention)
+	if err != nil {
+		r.log.WithError(err).Warn("Can't purge sent outbox events")
+		return
+	}
+	if deleted > 0 {
+		r.log.WithField("deleted", deleted).Info("Purged sent outbox events")
+	}
+}
+
+// outboxBackoff doubles the delay on every attempt: 1s, 2s, 4s... up to outboxMaxBackoff.
+func outboxBackoff(attempts int) time.Duration {
+	delay := time.Duration(math.Pow(2, float64(attempts))) * time.Second
+	if delay <= 0 || delay > outboxMaxBackoff {
+		return outboxMaxBackoff
+	}
+	return delay
+}
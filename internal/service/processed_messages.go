@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"go-api-template/internal/libs/database"
+	"go-api-template/internal/repositories"
+	"time"
+)
+
+// ProcessedMessageService makes message handlers idempotent by recording the handled message IDs.
+type ProcessedMessageService struct {
+	txRunner                   *database.TxRunner
+	ProcessedMessageRepository *repositories.ProcessedMessageRepository
+}
+
+func NewProcessedMessageService(txRunner *database.TxRunner, processedMessageRepository *repositories.ProcessedMessageRepository) *ProcessedMessageService {
+	return &ProcessedMessageService{txRunner: txRunner, ProcessedMessageRepository: processedMessageRepository}
+}
+
+// HandleOnce runs fn unless the consumer already processed messageID, and reports if fn ran.
+// The message is recorded in the transaction fn runs in: services called by fn with the same ctx
+// join it, so their changes and the record commit together. If fn fails both are rolled back
+// and the message is handled again on the next delivery.
+func (s *ProcessedMessageService) HandleOnce(ctx context.Context, consumer string, messageID string, fn func(ctx context.Context) error) (bool, error) {
+	handled := false
+	err := s.txRunner.WithTx(ctx, func(ctx context.Context, tx database.Querier) error {
+		handled = false
+
+		inserted, err := repositories.NewProcessedMessageRepository(tx).MarkProcessed(ctx, consumer, messageID)
+		if err != nil {
+			return err
+		}
+		if !inserted {
+			return nil
+		}
+
+		handled = true
+		return fn(ctx)
+	})
+	if err != nil {
+		return false, err
+	}
+	return handled, nil
+}
+
+// PurgeProcessedMessages forgets the messages processed more than retention ago and returns how many were deleted.
+func (s *ProcessedMessageService) PurgeProcessedMessages(ctx context.Context, retention time.Duration) (int64, error) {
+	return s.ProcessedMessageRepository.DeleteProcessedMessages(ctx, retention)
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"go-api-template/internal/libs/database"
+	"go-api-template/internal/repositories"
+	"testing"
+	"time"
+)
+
+func TestPurgeProcessedMessagesKeepsRecentMessages(t *testing.T) {
+	db := newTestDB(t)
+	if _, err := db.Exec("TRUNCATE processed_messages"); err != nil {
+		t.Fatalf("truncate: %v", err)
+	}
+	if _, err := db.Exec(`
+		INSERT INTO processed_messages (consumer, message_id, processed_at)
+		VALUES ('test', 'old', CURRENT_TIMESTAMP - INTERVAL '2 hours')`); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	processedMessages := NewProcessedMessageService(database.NewTxRunner(db), repositories.NewProcessedMessageRepository(db))
+	ctx := context.Background()
+	handled, err := processedMessages.HandleOnce(ctx, "test", "recent", func(ctx context.Context) error { return nil })
+	if err != nil || !handled {
+		t.Fatalf("HandleOnce = %v, %v", handled, err)
+	}
+
+	deleted, err := processedMessages.PurgeProcessedMessages(ctx, time.Hour)
+	if err != nil {
+		t.Fatalf("PurgeProcessedMessages: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("deleted %d messages, want only the old one", deleted)
+	}
+
+	handled, err = processedMessages.HandleOnce(ctx, "test", "recent", func(ctx context.Context) error {
+		t.Error("a message processed inside the retention was handled again")
+		return nil
+	})
+	if err != nil || handled {
+		t.Errorf("HandleOnce = %v, %v, want a duplicate", handled, err)
+	}
+}
@@ -1,23 +1,37 @@
 package service
 
 import (
-	"go-api-template/internal/libs/queue"
+	"go-api-template/internal/libs/database"
+	"go-api-template/internal/libs/pagination"
+	"go-api-template/internal/model"
 	"go-api-template/internal/repositories"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 )
 
 type Services struct {
-	UserService *UserService
+	UserService             *UserService
+	AuthService             *AuthService
+	ProcessedMessageService *ProcessedMessageService
 }
 
-func NewServices(db *sqlx.DB, publisher queue.Publisher) *Services {
-	userRepository := repositories.NewUserRepository(db)
+func NewServices(db *sqlx.DB) *Services {
+	txRunner := database.NewTxRunner(db)
+	querier := database.TraceQuerier(db)
 
-	userService := NewUserService(userRepository, publisher)
+	userRepository := repositories.NewUserRepository(querier)
+	refreshTokenRepository := repositories.NewRefreshTokenRepository(querier)
+	processedMessageRepository := repositories.NewProcessedMessageRepository(querier)
+
+	userService := NewUserService(txRunner, userRepository)
+	authService := NewAuthService(txRunner, userRepository, refreshTokenRepository)
+	processedMessageService := NewProcessedMessageService(txRunner, processedMessageRepository)
 
 	return &Services{
-		UserService: userService,
+		UserService:             userService,
+		AuthService:             authService,
+		ProcessedMessageService: processedMessageService,
 	}
 }
 
@@ -27,3 +41,35 @@ type CreateUserInput struct {
 	Email     string `json:"email"`
 	Password  string `json:"password"`
 }
+
+type LoginInput struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type TokenPair struct {
+	AccessToken           string
+	AccessTokenExpiresAt  time.Time
+	RefreshToken          string
+	RefreshTokenExpiresAt time.Time
+
+	refreshTokenID string
+}
+
+type UsersPage struct {
+	Users []model.User
+	Page  pagination.Page
+}
+
+type UpdateUserInput struct {
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Email     string `json:"email"`
+}
+
+// PatchUserInput fields that are not Set are left untouched, Set ones that are not Valid are cleared.
+type PatchUserInput struct {
+	FirstName model.NullString `json:"firstName"`
+	LastName  model.NullString `json:"lastName"`
+	Email     model.NullString `json:"email"`
+}
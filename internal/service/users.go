@@ -3,73 +3,200 @@ package service
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	errs "go-api-template/internal/errors"
 	"go-api-template/internal/libs/crypto"
+	"go-api-template/internal/libs/database"
+	"go-api-template/internal/libs/events"
+	"go-api-template/internal/libs/logger"
+	"go-api-template/internal/libs/pagination"
 	"go-api-template/internal/libs/queue"
 	"go-api-template/internal/model"
 	"go-api-template/internal/repositories"
 )
 
 type UserService struct {
+	txRunner       *database.TxRunner
 	UserRepository *repositories.UserRepository
-	Publisher      queue.Publisher
 }
 
-func NewUserService(userRepository *repositories.UserRepository, publisher queue.Publisher) *UserService {
-	return &UserService{UserRepository: userRepository, Publisher: publisher}
+func NewUserService(txRunner *database.TxRunner, userRepository *repositories.UserRepository) *UserService {
+	return &UserService{txRunner: txRunner, UserRepository: userRepository}
 }
 
 func (s *UserService) GetUserByID(ctx context.Context, id string) (any, error) {
-	fmt.Println("Getting user by ID: ", id)
+	logger.FromContext(ctx).WithField("id", id).Debug("Getting user by ID")
 	user, err := s.UserRepository.GetUser(ctx, id)
-
-	fmt.Println("User: ", err)
 	if err != nil {
-
 		return nil, err
 	}
 	return user, nil
 }
 
-func (s *UserService) CreateUser(ctx context.Context, user CreateUserInput) (model.User, error) {
-	emailExists, err := s.UserRepository.CheckIfUserEmailExists(ctx, user.Email)
+func (s *UserService) ListUsers(ctx context.Context, params pagination.Params) (UsersPage, error) {
+	users, page, err := s.UserRepository.ListUsers(ctx, params)
 	if err != nil {
-		return model.User{}, err
+		return UsersPage{}, err
 	}
-	if emailExists {
-		return model.User{}, errs.NewUserEmailExistsError(user.Email)
+
+	total, err := s.UserRepository.CountUsers(ctx)
+	if err != nil {
+		return UsersPage{}, err
 	}
 
+	return UsersPage{Users: users, Page: page.WithTotal(total)}, nil
+}
+
+func (s *UserService) CreateUser(ctx context.Context, user CreateUserInput) (model.User, error) {
 	hashedPassword, err := crypto.HashPassword(user.Password)
 	if err != nil {
 		return model.User{}, err
 	}
 
 	userModel := model.User{
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
+		FirstName: nullString(user.FirstName),
+		LastName:  nullString(user.LastName),
 		Email:     user.Email,
 		Password:  hashedPassword,
 	}
-	createdUser, err := s.UserRepository.CreateUser(ctx, userModel)
+
+	var createdUser model.User
+	err = s.txRunner.WithTx(ctx, func(ctx context.Context, tx database.Querier) error {
+		userRepository := repositories.NewUserRepository(tx)
+
+		emailExists, err := userRepository.CheckIfUserEmailExists(ctx, user.Email)
+		if err != nil {
+			return err
+		}
+		if emailExists {
+			return errs.NewUserEmailExistsError(user.Email)
+		}
+
+		createdUser, err = userRepository.CreateUser(ctx, userModel)
+		if err != nil {
+			// The email check above can race with another insert, the unique constraint has the last word.
+			if errs.IsUniqueViolation(err, model.UserEmailUniqueConstraint) {
+				return errs.NewUserEmailExistsError(user.Email)
+			}
+			return err
+		}
+
+		return addEvent(ctx, tx, model.UserCreatedEvent{
+			ID:        createdUser.ID,
+			Email:     createdUser.Email,
+			FirstName: createdUser.FirstName.String,
+			LastName:  createdUser.LastName.String,
+		})
+	})
 	if err != nil {
 		return model.User{}, err
 	}
+	return createdUser, nil
+}
+
+// UpdateUser replaces every editable field of the user.
+func (s *UserService) UpdateUser(ctx context.Context, id string, input UpdateUserInput) (model.User, error) {
+	return s.saveUser(ctx, id, func(user *model.User) {
+		user.FirstName = nullString(input.FirstName)
+		user.LastName = nullString(input.LastName)
+		user.Email = input.Email
+	})
+}
+
+// PatchUser only changes the fields that were sent, a null name is cleared.
+func (s *UserService) PatchUser(ctx context.Context, id string, input PatchUserInput) (model.User, error) {
+	// The email identifies the user, it can be changed but not cleared.
+	if input.Email.Set && !input.Email.Valid {
+		return model.User{}, errs.NewValidationFailedError([]errs.FieldError{{Field: "email", Rule: "required", Message: "is required"}})
+	}
 
-	if body, err := json.Marshal(map[string]any{
-		"id":        createdUser.ID,
-		"email":     createdUser.Email,
-		"firstName": createdUser.FirstName,
-		"lastName":  createdUser.LastName,
-	}); err == nil {
-		_ = s.Publisher.Publish(ctx, queue.Message{
-			Exchange:    queue.EventsExchangeName,
-			RoutingKey:  "users.created",
-			Body:        body,
-			ContentType: "application/json",
+	return s.saveUser(ctx, id, func(user *model.User) {
+		if input.FirstName.Set {
+			user.FirstName = input.FirstName
+		}
+		if input.LastName.Set {
+			user.LastName = input.LastName
+		}
+		if input.Email.Set {
+			user.Email = input.Email.String
+		}
+	})
+}
+
+func (s *UserService) DeleteUser(ctx context.Context, id string) error {
+	return s.txRunner.WithTx(ctx, func(ctx context.Context, tx database.Querier) error {
+		if err := repositories.NewUserRepository(tx).DeleteUser(ctx, id); err != nil {
+			return err
+		}
+		return addEvent(ctx, tx, model.UserDeletedEvent{ID: id})
+	})
+}
+
+// saveUser loads the user, applies the changes and saves it with its users.updated event in one transaction.
+func (s *UserService) saveUser(ctx context.Context, id string, apply func(user *model.User)) (model.User, error) {
+	var updatedUser model.User
+	err := s.txRunner.WithTx(ctx, func(ctx context.Context, tx database.Querier) error {
+		userRepository := repositories.NewUserRepository(tx)
+
+		user, err := userRepository.GetUser(ctx, id)
+		if err != nil {
+			return err
+		}
+		apply(&user)
+
+		emailExists, err := userRepository.CheckIfOtherUserEmailExists(ctx, user.ID, user.Email)
+		if err != nil {
+			return err
+		}
+		if emailExists {
+			return errs.NewUserEmailExistsError(user.Email)
+		}
+
+		updatedUser, err = userRepository.UpdateUser(ctx, user)
+		if err != nil {
+			if errs.IsUniqueViolation(err, model.UserEmailUniqueConstraint) {
+				return errs.NewUserEmailExistsError(user.Email)
+			}
+			return err
+		}
+
+		return addEvent(ctx, tx, model.UserUpdatedEvent{
+			ID:        updatedUser.ID,
+			Email:     updatedUser.Email,
+			FirstName: updatedUser.FirstName.String,
+			LastName:  updatedUser.LastName.String,
 		})
+	})
+	if err != nil {
+		return model.User{}, err
 	}
+	return updatedUser, nil
+}
 
-	return createdUser, nil
+// addEvent writes the event to the outbox with the transaction of the change, the outbox relay publishes it.
+func addEvent[T events.Event](ctx context.Context, tx database.Querier, event T) error {
+	msg, err := events.Message(ctx, queue.EventsExchangeName, events.NewEnvelope(event))
+	if err != nil {
+		return err
+	}
+
+	headers, err := json.Marshal(msg.Headers)
+	if err != nil {
+		return err
+	}
+
+	return repositories.NewOutboxRepository(tx).AddEvent(ctx, model.OutboxEvent{
+		ID:            msg.MessageID,
+		Exchange:      msg.Exchange,
+		RoutingKey:    msg.RoutingKey,
+		Payload:       msg.Body,
+		ContentType:   msg.ContentType,
+		MessageType:   nullString(msg.Type),
+		CorrelationID: nullString(msg.CorrelationID),
+		CausationID:   nullString(msg.CausationID),
+		Headers:       headers,
+	})
+}
+
+func nullString(s string) model.NullString {
+	return model.NullString{String: s, Valid: s != ""}
 }
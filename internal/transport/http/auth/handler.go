@@ -0,0 +1,61 @@
+package authHttpTransport
+
+import (
+	"go-api-template/internal/libs/renderer"
+	"go-api-template/internal/libs/utils"
+	"go-api-template/internal/service"
+	"net/http"
+)
+
+type AuthHandlers struct {
+	authService      *service.AuthService
+	responseRenderer *renderer.ResponseRenderer
+}
+
+func NewAuthHandlers(authService *service.AuthService, responseRenderer *renderer.ResponseRenderer) *AuthHandlers {
+	return &AuthHandlers{authService: authService, responseRenderer: responseRenderer}
+}
+
+func (h *AuthHandlers) Login(w http.ResponseWriter, r *http.Request) {
+	body, err := utils.GetJSONBody[LoginRequest](r)
+	if err != nil {
+		h.responseRenderer.JSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	tokens, err := h.authService.Login(r.Context(), toLoginInput(body))
+	if err != nil {
+		h.responseRenderer.JSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	h.responseRenderer.JSON(w, http.StatusOK, toTokenPairResponse(tokens))
+}
+
+func (h *AuthHandlers) Refresh(w http.ResponseWriter, r *http.Request) {
+	body, err := utils.GetJSONBody[RefreshTokenRequest](r)
+	if err != nil {
+		h.responseRenderer.JSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	tokens, err := h.authService.Refresh(r.Context(), body.RefreshToken)
+	if err != nil {
+		h.responseRenderer.JSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	h.responseRenderer.JSON(w, http.StatusOK, toTokenPairResponse(tokens))
+}
+
+func (h *AuthHandlers) Logout(w http.ResponseWriter, r *http.Request) {
+	body, err := utils.GetJSONBody[RefreshTokenRequest](r)
+	if err != nil {
+		h.responseRenderer.JSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.authService.Logout(r.Context(), body.RefreshToken); err != nil {
+		h.responseRenderer.JSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,11 @@
+package authHttpTransport
+
+import (
+	"github.com/go-chi/chi/v5"
+)
+
+func (h *AuthHandlers) RegisterRoutes(r chi.Router) {
+	r.Post("/login", h.Login)
+	r.Post("/refresh", h.Refresh)
+	r.Post("/logout", h.Logout)
+}
@@ -0,0 +1,20 @@
+package authHttpTransport
+
+import "go-api-template/internal/service"
+
+func toLoginInput(request LoginRequest) service.LoginInput {
+	return service.LoginInput{
+		Email:    request.Email,
+		Password: request.Password,
+	}
+}
+
+func toTokenPairResponse(tokens service.TokenPair) TokenPairResponse {
+	return TokenPairResponse{
+		AccessToken:           tokens.AccessToken,
+		AccessTokenExpiresAt:  tokens.AccessTokenExpiresAt,
+		RefreshToken:          tokens.RefreshToken,
+		RefreshTokenExpiresAt: tokens.RefreshTokenExpiresAt,
+		TokenType:             "Bearer",
+	}
+}
@@ -3,11 +3,13 @@ package httpTransport
 import (
 	"go-api-template/internal/libs/renderer"
 	"go-api-template/internal/service"
+	authHttpTransport "go-api-template/internal/transport/http/auth"
 	usersHttpTransport "go-api-template/internal/transport/http/users"
 )
 
 type HTTPTransport struct {
 	Users *usersHttpTransport.UserHandlers
+	Auth  *authHttpTransport.AuthHandlers
 	// others, ex: Orders *ordersHttpTransport.OrderHandlers
 }
 
@@ -15,5 +17,6 @@ func NewHTTPTransport(services *service.Services, responseRenderer *renderer.Res
 
 	return &HTTPTransport{
 		Users: usersHttpTransport.NewUserHandlers(services.UserService, responseRenderer),
+		Auth:  authHttpTransport.NewAuthHandlers(services.AuthService, responseRenderer),
 	}
 }
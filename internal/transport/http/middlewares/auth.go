@@ -1,16 +1,78 @@
 package middlewares
 
 import (
+	"context"
+	"errors"
+	errs "go-api-template/internal/errors"
+	"go-api-template/internal/libs/auth"
+	"go-api-template/internal/libs/renderer"
 	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
 )
 
+type claimsContextKey struct{}
+
 // AuthMiddleware validates the Authorization header (Bearer token) and stores claims in context
-func AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		// if r.Header.Get("Authorization") == "" {
-		// 	http.Error(w, "unauthorized", http.StatusUnauthorized)
-		// 	return
-		// }
-		next(w, r)
+func AuthMiddleware(responseRenderer *renderer.ResponseRenderer) Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			token, ok := bearerToken(r)
+			if !ok {
+				responseRenderer.JSON(w, http.StatusUnauthorized, errs.NewUnauthorizedError("Missing bearer token"))
+				return
+			}
+
+			claims, err := auth.ParseAccessToken(token)
+			if err != nil {
+				message := "Invalid token"
+				if errors.Is(err, jwt.ErrTokenExpired) {
+					message = "Token expired"
+				}
+				responseRenderer.JSON(w, http.StatusUnauthorized, errs.NewUnauthorizedError(message))
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), claimsContextKey{}, claims)
+			ctx = setRequestUserID(ctx, claims.UserID())
+			next(w, r.WithContext(ctx))
+		}
+	}
+}
+
+// SelfMiddleware only lets the authenticated user act on their own resource: the URL param must be the
+// subject of the token, otherwise it is a 403. It must run after AuthMiddleware.
+func SelfMiddleware(responseRenderer *renderer.ResponseRenderer, param string) Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := ClaimsFromContext(r.Context())
+			if !ok {
+				responseRenderer.JSON(w, http.StatusUnauthorized, errs.NewUnauthorizedError("Missing bearer token"))
+				return
+			}
+			if claims.UserID() != chi.URLParam(r, param) {
+				responseRenderer.JSON(w, http.StatusForbidden, errs.NewForbiddenError("You can only change your own user"))
+				return
+			}
+			next(w, r)
+		}
+	}
+}
+
+// ClaimsFromContext returns the claims stored by AuthMiddleware.
+func ClaimsFromContext(ctx context.Context) (*auth.Claims, bool) {
+	claims, ok := ctx.Value(claimsContextKey{}).(*auth.Claims)
+	return claims, ok && claims != nil
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
 	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
 }
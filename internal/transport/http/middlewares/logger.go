@@ -0,0 +1,82 @@
+package middlewares
+
+import (
+	"context"
+	"go-api-template/internal/libs/correlation"
+	"go-api-template/internal/libs/logger"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type requestInfoContextKey struct{}
+
+// requestInfo collects what is only known deeper in the handler chain, for the access log.
+type requestInfo struct {
+	userID string
+}
+
+// LoggerMiddleware stores a request-scoped logger with the request ID and method in the context and
+// logs every request once it is served, with its route pattern, status, latency and user ID.
+// It must run after RequestIDMiddleware and TracingMiddleware.
+func LoggerMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		info := &requestInfo{}
+
+		fields := logrus.Fields{
+			"requestId": correlation.CorrelationID(r.Context()),
+			"method":    r.Method,
+			"path":      r.URL.Path,
+		}
+		if spanCtx := trace.SpanContextFromContext(r.Context()); spanCtx.IsValid() {
+			fields["traceId"] = spanCtx.TraceID().String()
+		}
+
+		ctx := context.WithValue(r.Context(), requestInfoContextKey{}, info)
+		ctx = logger.WithLogger(ctx, logrus.WithFields(fields))
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		fields = logrus.Fields{
+			"status":  status,
+			"latency": time.Since(start),
+			"bytes":   ww.BytesWritten(),
+		}
+		// The route pattern is only known once chi routed the request.
+		if routeCtx := chi.RouteContext(ctx); routeCtx != nil && routeCtx.RoutePattern() != "" {
+			fields["route"] = routeCtx.RoutePattern()
+		}
+		if info.userID != "" {
+			fields["userId"] = info.userID
+		}
+
+		log := logger.FromContext(ctx).WithFields(fields)
+		switch {
+		case status >= http.StatusInternalServerError:
+			log.Error("Request served")
+		case status >= http.StatusBadRequest:
+			log.Warn("Request served")
+		default:
+			log.Info("Request served")
+		}
+	})
+}
+
+// setRequestUserID adds the authenticated user to the request logger and the access log.
+func setRequestUserID(ctx context.Context, userID string) context.Context {
+	if info, ok := ctx.Value(requestInfoContextKey{}).(*requestInfo); ok {
+		info.userID = userID
+	}
+	return logger.WithFields(ctx, logrus.Fields{"userId": userID})
+}
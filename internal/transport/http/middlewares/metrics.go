@@ -0,0 +1,38 @@
+package middlewares
+
+import (
+	"go-api-template/internal/libs/metrics"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// MetricsMiddleware counts the requests and records their latency by method, route pattern and status.
+// Requests no route matched share the "unmatched" route, so random paths don't create new series.
+func MetricsMiddleware(m *metrics.Metrics) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			route := "unmatched"
+			if routeCtx := chi.RouteContext(r.Context()); routeCtx != nil && routeCtx.RoutePattern() != "" {
+				route = routeCtx.RoutePattern()
+			}
+
+			labels := []string{r.Method, route, strconv.Itoa(status)}
+			m.HTTPRequests.WithLabelValues(labels...).Inc()
+			m.HTTPRequestDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+		})
+	}
+}
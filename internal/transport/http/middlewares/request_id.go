@@ -0,0 +1,30 @@
+package middlewares
+
+import (
+	"context"
+	"go-api-template/internal/libs/correlation"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+)
+
+// maxRequestIDLength bounds the request IDs accepted from clients, they end up in logs and messages.
+const maxRequestIDLength = 128
+
+// RequestIDMiddleware uses the X-Request-ID header of the request, or generates one, echoes it in the
+// response and stores it in the context as the correlation ID. The chi request ID is set too, for chi
+// middlewares that read it.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(correlation.RequestIDHeader)
+		if requestID == "" || len(requestID) > maxRequestIDLength {
+			requestID = uuid.NewString()
+		}
+		w.Header().Set(correlation.RequestIDHeader, requestID)
+
+		ctx := correlation.WithCorrelationID(r.Context(), requestID)
+		ctx = context.WithValue(ctx, middleware.RequestIDKey, requestID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,59 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"go-api-template/internal/libs/correlation"
+	"go-api-template/internal/libs/renderer"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	responseRenderer := renderer.NewResponseRenderer()
+	var contextID string
+	handler := RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contextID = correlation.CorrelationID(r.Context())
+		responseRenderer.JSONData(w, http.StatusOK, "ok")
+	}))
+
+	tests := []struct {
+		name     string
+		header   string
+		wantSame bool
+	}{
+		{name: "from the client", header: "client-id-1", wantSame: true},
+		{name: "generated when missing", header: ""},
+		{name: "generated when too long", header: strings.Repeat("a", maxRequestIDLength+1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(correlation.RequestIDHeader, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			requestID := rec.Header().Get(correlation.RequestIDHeader)
+			if requestID == "" {
+				t.Fatal("the response has no request ID")
+			}
+			if (requestID == tt.header) != tt.wantSame {
+				t.Errorf("request ID = %q, client sent %q", requestID, tt.header)
+			}
+			if contextID != requestID {
+				t.Errorf("context correlation ID = %q, want %q", contextID, requestID)
+			}
+
+			var body renderer.SuccessfulResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decode body: %v", err)
+			}
+			if body.RequestID != requestID {
+				t.Errorf("envelope requestId = %q, want %q", body.RequestID, requestID)
+			}
+		})
+	}
+}
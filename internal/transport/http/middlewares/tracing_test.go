@@ -0,0 +1,88 @@
+package middlewares
+
+import (
+	"context"
+	"go-api-template/internal/libs/tracing"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracingMiddleware(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := tracing.NewTracerProvider(sdktrace.WithSampler(sdktrace.AlwaysSample()), sdktrace.WithSpanProcessor(recorder))
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+		_ = tp.Shutdown(context.Background())
+	})
+
+	r := chi.NewRouter()
+	r.Use(TracingMiddleware)
+	r.Get("/api/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	r.Get("/api/fail", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/api/users/42", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/fail", nil))
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+
+	span := spans[0]
+	if span.Name() != "GET /api/users/{id}" {
+		t.Errorf("span name = %q, want the route pattern", span.Name())
+	}
+	if span.SpanKind() != trace.SpanKindServer {
+		t.Errorf("span kind = %s, want server", span.SpanKind())
+	}
+	if span.SpanContext().TraceID().String() != traceID {
+		t.Errorf("trace ID = %s, want the caller's %s", span.SpanContext().TraceID(), traceID)
+	}
+	for _, want := range []attribute.KeyValue{
+		semconv.HTTPRouteKey.String("/api/users/{id}"),
+		semconv.URLPathKey.String("/api/users/42"),
+		semconv.HTTPResponseStatusCodeKey.Int(http.StatusNotFound),
+	} {
+		if !hasAttribute(span.Attributes(), want) {
+			t.Errorf("missing attribute %s=%s", want.Key, want.Value.Emit())
+		}
+	}
+	if span.Status().Code == codes.Error {
+		t.Error("a 4xx response is recorded as an error")
+	}
+
+	if spans[1].Status().Code != codes.Error {
+		t.Errorf("5xx span status = %+v, want an error", spans[1].Status())
+	}
+}
+
+func hasAttribute(attrs []attribute.KeyValue, want attribute.KeyValue) bool {
+	for _, attr := range attrs {
+		if attr == want {
+			return true
+		}
+	}
+	return false
+}
@@ -1,6 +1,7 @@
 package usersHttpTransport
 
 import (
+	"go-api-template/internal/libs/pagination"
 	"go-api-template/internal/libs/renderer"
 	"go-api-template/internal/libs/utils"
 	"go-api-template/internal/service"
@@ -43,3 +44,59 @@ func (h *UserHandlers) CreateUser(w http.ResponseWriter, r *http.Request) {
 	}
 	h.responseRenderer.JSON(w, http.StatusOK, createdUser)
 }
+
+func (h *UserHandlers) ListUsers(w http.ResponseWriter, r *http.Request) {
+	params, err := pagination.ParseParams(r, listUsersPaginationOptions())
+	if err != nil {
+		h.responseRenderer.JSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	page, err := h.userService.ListUsers(r.Context(), params)
+	if err != nil {
+		h.responseRenderer.JSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	h.responseRenderer.JSONPage(w, r, http.StatusOK, page.Users, page.Page)
+}
+
+func (h *UserHandlers) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	user, err := utils.GetJSONBody[UpdateUserRequest](r)
+	if err != nil {
+		h.responseRenderer.JSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	updatedUser, err := h.userService.UpdateUser(r.Context(), id, toUpdateUserInput(user))
+	if err != nil {
+		h.responseRenderer.JSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	h.responseRenderer.JSON(w, http.StatusOK, updatedUser)
+}
+
+func (h *UserHandlers) PatchUser(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	user, err := utils.GetJSONBody[PatchUserRequest](r)
+	if err != nil {
+		h.responseRenderer.JSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	updatedUser, err := h.userService.PatchUser(r.Context(), id, toPatchUserInput(user))
+	if err != nil {
+		h.responseRenderer.JSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	h.responseRenderer.JSON(w, http.StatusOK, updatedUser)
+}
+
+func (h *UserHandlers) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if err := h.userService.DeleteUser(r.Context(), id); err != nil {
+		h.responseRenderer.JSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
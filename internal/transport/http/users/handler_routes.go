@@ -7,6 +7,17 @@ import (
 )
 
 func (h *UserHandlers) RegisterRoutes(r chi.Router) {
-	r.Get("/{id}", h.GetUser)
-	r.Post("/", middlewares.ChainMiddlewares(h.CreateUser, middlewares.AuthMiddleware))
+	authMiddleware := middlewares.AuthMiddleware(h.responseRenderer)
+
+	// A user can only change or delete themselves.
+	selfMiddleware := middlewares.SelfMiddleware(h.responseRenderer, "id")
+
+	// Users are listed with their email, only for authenticated callers.
+	r.Get("/", middlewares.ChainMiddlewares(h.ListUsers, authMiddleware))
+	r.Get("/{id}", middlewares.ChainMiddlewares(h.GetUser, authMiddleware))
+	// Signup: creating a user is public, it is how the first account of a deployment gets created.
+	r.Post("/", h.CreateUser)
+	r.Put("/{id}", middlewares.ChainMiddlewares(h.UpdateUser, authMiddleware, selfMiddleware))
+	r.Patch("/{id}", middlewares.ChainMiddlewares(h.PatchUser, authMiddleware, selfMiddleware))
+	r.Delete("/{id}", middlewares.ChainMiddlewares(h.DeleteUser, authMiddleware, selfMiddleware))
 }
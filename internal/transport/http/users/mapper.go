@@ -1,6 +1,29 @@
 package usersHttpTransport
 
-import "go-api-template/internal/service"
+import (
+	"go-api-template/config"
+	"go-api-template/internal/libs/pagination"
+	"go-api-template/internal/repositories"
+	"go-api-template/internal/service"
+)
+
+// listUsersPaginationOptions maps the sort query param names to user columns.
+// The names can be NULL, they are sorted as empty strings so the keyset comparisons don't skip them.
+// It reads the cursor secret from the loaded config.
+func listUsersPaginationOptions() pagination.Options {
+	return pagination.Options{
+		DefaultLimit: 20,
+		MaxLimit:     100,
+		SortableFields: map[string]string{
+			"createdAt": "created_at",
+			"email":     "email",
+			"firstName": repositories.UserFirstNameSortColumn,
+			"lastName":  repositories.UserLastNameSortColumn,
+		},
+		DefaultSort:  []pagination.SortField{{Column: "created_at"}},
+		CursorSecret: []byte(config.CONFIG.PaginationCursorSecret),
+	}
+}
 
 func toCreateUserInput(request CreateUserRequest) service.CreateUserInput {
 	return service.CreateUserInput{
@@ -10,3 +33,19 @@ func toCreateUserInput(request CreateUserRequest) service.CreateUserInput {
 		Password:  request.Password,
 	}
 }
+
+func toUpdateUserInput(request UpdateUserRequest) service.UpdateUserInput {
+	return service.UpdateUserInput{
+		FirstName: request.FirstName,
+		LastName:  request.LastName,
+		Email:     request.Email,
+	}
+}
+
+func toPatchUserInput(request PatchUserRequest) service.PatchUserInput {
+	return service.PatchUserInput{
+		FirstName: request.FirstName,
+		LastName:  request.LastName,
+		Email:     request.Email,
+	}
+}
@@ -1,8 +1,24 @@
 package usersHttpTransport
 
+import "go-api-template/internal/model"
+
 type CreateUserRequest struct {
-	FirstName string `json:"firstName"`
-	LastName  string `json:"lastName"`
-	Email     string `json:"email"`
-	Password  string `json:"password"`
+	FirstName string `json:"firstName" validate:"required,max=255"`
+	LastName  string `json:"lastName" validate:"required,max=255"`
+	Email     string `json:"email" validate:"required,email,max=255"`
+	Password  string `json:"password" validate:"required,min=8,max=72"`
+}
+
+type UpdateUserRequest struct {
+	FirstName string `json:"firstName" validate:"required,max=255"`
+	LastName  string `json:"lastName" validate:"required,max=255"`
+	Email     string `json:"email" validate:"required,email,max=255"`
+}
+
+// PatchUserRequest fields that are omitted are not changed and null names are cleared.
+// Present values are validated even when empty, a null email is rejected by the service.
+type PatchUserRequest struct {
+	FirstName model.NullString `json:"firstName" validate:"omitempty,min=1,max=255"`
+	LastName  model.NullString `json:"lastName" validate:"omitempty,min=1,max=255"`
+	Email     model.NullString `json:"email" validate:"omitempty,email,max=255"`
 }
@@ -0,0 +1,39 @@
+package queueTransport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-api-template/internal/libs/events"
+	"go-api-template/internal/libs/queue"
+)
+
+// TypedHandler handles a message whose payload was decoded into T.
+type TypedHandler[T any] func(ctx context.Context, d queue.Delivery, payload T) error
+
+// DecodeJSON adapts a TypedHandler to a queue handler by decoding the JSON body into T.
+// A body that can't be decoded will never succeed, so the message is dead-lettered right away.
+func DecodeJSON[T any](h TypedHandler[T]) queue.RabbitMQHandler {
+	return func(ctx context.Context, d queue.Delivery) error {
+		var payload T
+		if err := json.Unmarshal(d.Body, &payload); err != nil {
+			return fmt.Errorf("%w: decode %s payload: %w", queue.ErrDeadLetter, d.RoutingKey, err)
+		}
+		return h(ctx, d, payload)
+	}
+}
+
+// EventHandler handles a typed event, upcast to the version the handler knows.
+type EventHandler[T events.Event] func(ctx context.Context, d queue.Delivery, event events.Envelope[T]) error
+
+// HandleEvent adapts an EventHandler to a queue handler by decoding the event envelope. Events that
+// can't be decoded or upcast are dead-lettered, they can be replayed once the consumer knows their version.
+func HandleEvent[T events.Event](h EventHandler[T]) queue.RabbitMQHandler {
+	return func(ctx context.Context, d queue.Delivery) error {
+		event, err := events.Decode[T](d.Body)
+		if err != nil {
+			return fmt.Errorf("%w: decode %s event: %w", queue.ErrDeadLetter, d.RoutingKey, err)
+		}
+		return h(ctx, d, event)
+	}
+}
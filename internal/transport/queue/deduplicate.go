@@ -0,0 +1,56 @@
+package queueTransport
+
+import (
+	"context"
+	"errors"
+	"go-api-template/internal/libs/logger"
+	"go-api-template/internal/libs/queue"
+	"go-api-template/internal/service"
+	"time"
+)
+
+// DeduplicateMiddleware acks the messages the consumer already processed without running the handler again.
+// consumer names the handler (usually its queue), a message fanned out to several consumers is processed
+// once by each. Messages without a message ID are always handled.
+func DeduplicateMiddleware(processedMessages *service.ProcessedMessageService, consumer string) Middleware {
+	return func(next queue.RabbitMQHandler) queue.RabbitMQHandler {
+		return func(ctx context.Context, d queue.Delivery) error {
+			if d.MessageID == "" {
+				return next(ctx, d)
+			}
+
+			handled, err := processedMessages.HandleOnce(ctx, consumer, d.MessageID, func(ctx context.Context) error {
+				return next(ctx, d)
+			})
+			if err != nil {
+				return err
+			}
+			if !handled {
+				logger.FromContext(ctx).WithField("consumer", consumer).Info("Skipped duplicate message")
+			}
+			return nil
+		}
+	}
+}
+
+// runProcessedMessagesPurge deletes the processed messages older than retention every hour until ctx is done.
+func runProcessedMessagesPurge(ctx context.Context, processedMessages *service.ProcessedMessageService, retention time.Duration) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := processedMessages.PurgeProcessedMessages(ctx, retention)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			logger.FromContext(ctx).WithError(err).Warn("Can't purge processed messages")
+		}
+		if deleted > 0 {
+			logger.FromContext(ctx).WithField("deleted", deleted).Info("Purged processed messages")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
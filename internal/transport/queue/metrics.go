@@ -0,0 +1,75 @@
+package queueTransport
+
+import (
+	"context"
+	"go-api-template/internal/libs/queue"
+	"sync"
+	"time"
+)
+
+// HandlerObserver receives the outcome of every handled message, see MetricsMiddleware.
+type HandlerObserver interface {
+	ObserveHandler(routingKey queue.RoutingKey, duration time.Duration, err error)
+}
+
+// HandlerObservers reports to every observer of the list.
+type HandlerObservers []HandlerObserver
+
+func (o HandlerObservers) ObserveHandler(routingKey queue.RoutingKey, duration time.Duration, err error) {
+	for _, observer := range o {
+		observer.ObserveHandler(routingKey, duration, err)
+	}
+}
+
+// MetricsMiddleware reports the duration and result of the handler to observer.
+func MetricsMiddleware(observer HandlerObserver) Middleware {
+	return func(next queue.RabbitMQHandler) queue.RabbitMQHandler {
+		return func(ctx context.Context, d queue.Delivery) error {
+			start := time.Now()
+			err := next(ctx, d)
+			observer.ObserveHandler(d.RoutingKey, time.Since(start), err)
+			return err
+		}
+	}
+}
+
+// HandlerStats is an in-memory HandlerObserver counting the handled messages per routing key.
+type HandlerStats struct {
+	mu    sync.Mutex
+	stats map[queue.RoutingKey]HandlerStat
+}
+
+type HandlerStat struct {
+	Handled       int64
+	Failed        int64
+	TotalDuration time.Duration
+}
+
+func NewHandlerStats() *HandlerStats {
+	return &HandlerStats{stats: map[queue.RoutingKey]HandlerStat{}}
+}
+
+func (s *HandlerStats) ObserveHandler(routingKey queue.RoutingKey, duration time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stat := s.stats[routingKey]
+	stat.Handled++
+	if err != nil {
+		stat.Failed++
+	}
+	stat.TotalDuration += duration
+	s.stats[routingKey] = stat
+}
+
+// Snapshot returns a copy of the counters.
+func (s *HandlerStats) Snapshot() map[queue.RoutingKey]HandlerStat {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := make(map[queue.RoutingKey]HandlerStat, len(s.stats))
+	for k, v := range s.stats {
+		snapshot[k] = v
+	}
+	return snapshot
+}
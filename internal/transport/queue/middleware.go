@@ -0,0 +1,97 @@
+package queueTransport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-api-template/internal/libs/logger"
+	"go-api-template/internal/libs/queue"
+	"runtime/debug"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type Middleware func(queue.RabbitMQHandler) queue.RabbitMQHandler
+
+func ChainMiddlewares(h queue.RabbitMQHandler, middlewares ...Middleware) queue.RabbitMQHandler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	return h
+}
+
+// ErrHandlerPanic is returned by RecoverMiddleware when the handler panicked.
+var ErrHandlerPanic = errors.New("message handler panicked")
+
+// RecoverMiddleware turns a handler panic into an error, so the message fails (and is retried or
+// dead-lettered) like any other failed message instead of crashing the process.
+func RecoverMiddleware(next queue.RabbitMQHandler) queue.RabbitMQHandler {
+	return func(ctx context.Context, d queue.Delivery) (err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				logger.FromContext(ctx).WithFields(logrus.Fields{
+					"routingKey": d.RoutingKey,
+					"messageId":  d.MessageID,
+					"panic":      p,
+					"stack":      string(debug.Stack()),
+				}).Error("Message handler panicked")
+				err = fmt.Errorf("%w: %v", ErrHandlerPanic, p)
+			}
+		}()
+		return next(ctx, d)
+	}
+}
+
+// LoggingMiddleware stores a message-scoped logger in the handler ctx and logs every handled message with
+// its metadata, the handling duration and the error, if any.
+func LoggingMiddleware(next queue.RabbitMQHandler) queue.RabbitMQHandler {
+	return func(ctx context.Context, d queue.Delivery) error {
+		start := time.Now()
+		ctx = logger.WithFields(ctx, logrus.Fields{
+			"routingKey":    d.RoutingKey,
+			"messageId":     d.MessageID,
+			"correlationId": d.CorrelationID,
+		})
+		err := next(ctx, d)
+
+		log := logger.FromContext(ctx).WithFields(logrus.Fields{
+			"type":          d.Type,
+			"deliveryCount": d.DeliveryCount,
+			"redelivered":   d.Redelivered,
+			"duration":      time.Since(start),
+		})
+		if err != nil {
+			log.WithError(err).Warn("Message handling failed")
+			return err
+		}
+		log.Info("Message handled")
+		return nil
+	}
+}
+
+// TimeoutMiddleware cancels the handler ctx after timeout. The handler has to respect ctx, its error is then
+// context.DeadlineExceeded and the message is retried.
+func TimeoutMiddleware(timeout time.Duration) Middleware {
+	return func(next queue.RabbitMQHandler) queue.RabbitMQHandler {
+		return func(ctx context.Context, d queue.Delivery) error {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			return next(ctx, d)
+		}
+	}
+}
+
+// TracingMiddleware extracts the trace context of the publisher from the message headers into the handler ctx,
+// so the spans and logs of the handler continue the publisher's trace. The brokers already start the consumer
+// span with it, the middleware only matters when the handler is called without one.
+func TracingMiddleware(next queue.RabbitMQHandler) queue.RabbitMQHandler {
+	return func(ctx context.Context, d queue.Delivery) error {
+		if len(d.Headers) > 0 && !trace.SpanContextFromContext(ctx).IsValid() {
+			ctx = otel.GetTextMapPropagator().Extract(ctx, queue.HeaderCarrier(d.Headers))
+		}
+		return next(ctx, d)
+	}
+}
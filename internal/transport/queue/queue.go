@@ -5,52 +5,144 @@ import (
 	"go-api-template/config"
 	"go-api-template/internal/libs/queue"
 	"go-api-template/internal/service"
+	"sync"
+	"time"
 )
 
 type QueueTransport struct {
 	services *service.Services
-	rabbit   *queue.RabbitMQ
+	consumer queue.Consumer
+	replier  queue.Replier
+
+	// HandlerStats counts the handled messages per routing key.
+	HandlerStats *HandlerStats
+	// observers also receive the outcome of every handled message, ex: the Prometheus metrics.
+	observers HandlerObservers
+
+	// routers holds the router of each consumed queue, by queue name.
+	routers map[string]*Router
 }
 
-func NewQueueTransport(services *service.Services, rabbit *queue.RabbitMQ) *QueueTransport {
-	return &QueueTransport{
-		services: services,
-		rabbit:   rabbit,
+// NewQueueTransport takes the configured broker, nil when no broker is enabled. The observers are
+// told about every handled message, with HandlerStats.
+func NewQueueTransport(services *service.Services, broker queue.Broker, observers ...HandlerObserver) *QueueTransport {
+	t := &QueueTransport{
+		services:     services,
+		HandlerStats: NewHandlerStats(),
+	}
+	t.observers = append(HandlerObservers{t.HandlerStats}, observers...)
+	if broker != nil {
+		t.consumer, t.replier = broker, broker
 	}
+	t.routers = t.registerHandlers()
+	return t
 }
 
-func (t *QueueTransport) StartConsumers(ctx context.Context) error {
-	if !config.CONFIG.RabbitMQEnabled || t.rabbit == nil {
-		return nil
+func (t *QueueTransport) registerHandlers() map[string]*Router {
+	// Handlers that must not run twice for the same message opt in to deduplication.
+	deduplicate := func(consumer string) Middleware {
+		return DeduplicateMiddleware(t.services.ProcessedMessageService, consumer)
 	}
 
-	messagesRouter := NewRouter()
-	messagesRouter.RegisterHandler(queue.UsersCreatedRoutingKey, HandleUsersCreated)
+	usersCreatedRouter := t.newRouter()
+	usersCreatedRouter.RegisterHandler(queue.UsersCreatedRoutingKey, ChainMiddlewares(
+		HandleEvent(HandleUsersCreated),
+		TimeoutMiddleware(30*time.Second),
+		deduplicate(queue.UsersCreatedQueueName),
+	))
+	// The queue is only bound to the keys above, anything else is a misrouted message worth keeping.
+	usersCreatedRouter.SetFallback(DeadLetterUnmatched)
+
+	// Requests are answered, not retried: a failed request gets an error reply.
+	usersRPCRouter := t.newRouter()
+	usersRPCRouter.RegisterHandler(queue.UsersGetRoutingKey, ChainMiddlewares(
+		ReplyTo(t.replier, HandleRequest(t.HandleGetUser)),
+		TimeoutMiddleware(10*time.Second),
+	))
+	usersRPCRouter.SetFallback(DeadLetterUnmatched)
 
-	type consumerDef struct {
-		exchange string
-		queue    string
+	return map[string]*Router{
+		queue.UsersCreatedQueueName: usersCreatedRouter,
+		queue.UsersRPCQueueName:     usersRPCRouter,
 	}
+}
+
+// newRouter returns a router with the middlewares every handler gets. Recovery comes first so it also
+// catches panics of the other middlewares.
+func (t *QueueTransport) newRouter() *Router {
+	router := NewRouter()
+	router.Use(
+		RecoverMiddleware,
+		TracingMiddleware,
+		LoggingMiddleware,
+		MetricsMiddleware(t.observers),
+	)
+	return router
+}
 
-	consumers := []consumerDef{
-		{
-			exchange: queue.EventsExchangeName,
-			queue:    queue.UsersCreatedQueueName,
-		},
+// Topology returns the owned queues with a binding for every key or pattern their router handles.
+func (t *QueueTransport) Topology() []queue.QueueSpec {
+	specs := queue.OwnedQueues()
+	for i, spec := range specs {
+		router, ok := t.routers[spec.Name]
+		if !ok {
+			continue
+		}
+
+		bindings := append([]queue.Binding{}, spec.Bindings...)
+		for _, b := range router.Bindings() {
+			if !containsBinding(bindings, b) {
+				bindings = append(bindings, b)
+			}
+		}
+		specs[i].Bindings = bindings
+	}
+	return specs
+}
+
+func (t *QueueTransport) StartConsumers(ctx context.Context) error {
+	if t.consumer == nil {
+		return nil
 	}
 
 	// Consume Queues
-	errCh := make(chan error, len(consumers))
-	for _, c := range consumers {
-		go func(c consumerDef) {
-			errCh <- t.rabbit.Consume(ctx, c.queue, messagesRouter.Handle)
-		}(c)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go runProcessedMessagesPurge(ctx, t.services.ProcessedMessageService, config.CONFIG.ProcessedMessagesRetention)
+
+	var wg sync.WaitGroup
+	errCh := make(chan error, len(t.routers))
+	for _, spec := range queue.OwnedQueues() {
+		router, ok := t.routers[spec.Name]
+		if !ok {
+			continue
+		}
+
+		wg.Add(1)
+		go func(queueName string, router *Router) {
+			defer wg.Done()
+			errCh <- t.consumer.Consume(ctx, queueName, router.Handle)
+		}(spec.Name, router)
 	}
 
+	// Return once every consumer stopped, so in-flight messages are finished before shutdown.
+	var err error
 	select {
 	case <-ctx.Done():
-		return ctx.Err()
-	case err := <-errCh:
-		return err
+		err = ctx.Err()
+	case err = <-errCh:
+		cancel()
+	}
+	wg.Wait()
+	return err
+}
+
+func containsBinding(bindings []queue.Binding, b queue.Binding) bool {
+	for _, existing := range bindings {
+		if existing.RoutingKey == b.RoutingKey {
+			return true
+		}
 	}
+	return false
 }
@@ -2,17 +2,31 @@ package queueTransport
 
 import (
 	"context"
+	"go-api-template/internal/libs/events"
+	"go-api-template/internal/libs/logger"
 	"go-api-template/internal/libs/queue"
+	"go-api-template/internal/model"
 
 	"github.com/sirupsen/logrus"
 )
 
-func HandleUsersCreated(ctx context.Context, d queue.Delivery) error {
-	logrus.WithFields(logrus.Fields{
-		"routingKey": d.RoutingKey,
-		"bodySize":   len(d.Body),
-	}).Info("Consumed message")
+func HandleUsersCreated(ctx context.Context, d queue.Delivery, event events.Envelope[model.UserCreatedEvent]) error {
+	logger.FromContext(ctx).WithFields(logrus.Fields{
+		"eventId":    event.ID,
+		"occurredAt": event.OccurredAt,
+		"userId":     event.Data.ID,
+	}).Info("Consumed users.created")
 
 	// TODO: Handle the message in any way you want
 	return nil
 }
+
+// GetUserRequest is the payload of the users.get request.
+type GetUserRequest struct {
+	ID string `json:"id"`
+}
+
+// HandleGetUser answers users.get with the user, for services that need it synchronously.
+func (t *QueueTransport) HandleGetUser(ctx context.Context, d queue.Delivery, request GetUserRequest) (any, error) {
+	return t.services.UserService.GetUserByID(ctx, request.ID)
+}
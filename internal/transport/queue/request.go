@@ -0,0 +1,87 @@
+package queueTransport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	errs "go-api-template/internal/errors"
+	"go-api-template/internal/libs/logger"
+	"go-api-template/internal/libs/queue"
+	"net/http"
+)
+
+// RequestHandler handles a request and returns the body of the reply.
+type RequestHandler func(ctx context.Context, d queue.Delivery) ([]byte, error)
+
+// TypedRequestHandler handles a request whose payload was decoded into Req.
+type TypedRequestHandler[Req any, Resp any] func(ctx context.Context, d queue.Delivery, request Req) (Resp, error)
+
+// HandleRequest adapts a TypedRequestHandler to a RequestHandler, the request and the reply are JSON.
+func HandleRequest[Req any, Resp any](h TypedRequestHandler[Req, Resp]) RequestHandler {
+	return func(ctx context.Context, d queue.Delivery) ([]byte, error) {
+		var request Req
+		if err := json.Unmarshal(d.Body, &request); err != nil {
+			return nil, queue.NewRPCError(queue.RPCCodeInvalidRequest, "Invalid request body", fmt.Errorf("decode %s request: %w", d.RoutingKey, err))
+		}
+
+		response, err := h(ctx, d, request)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(response)
+	}
+}
+
+// ReplyTo adapts a RequestHandler to a queue handler that sends its result to the requester.
+// A failed request is answered with queue.ErrorReply and acked, the requester decides whether to
+// retry. Requests without a reply-to have nobody to answer and are dead-lettered.
+//
+// Requests are never retried by the broker: the requester is waiting, so a reply that can't be
+// sent is only logged and the request is acked, the requester times out.
+func ReplyTo(replier queue.Replier, h RequestHandler) queue.RabbitMQHandler {
+	return func(ctx context.Context, d queue.Delivery) error {
+		if d.ReplyTo == "" {
+			return fmt.Errorf("%w: %w", queue.ErrDeadLetter, queue.ErrNoReplyTo)
+		}
+
+		log := logger.FromContext(ctx)
+		response := queue.Message{ContentType: "application/json"}
+		body, err := h(ctx, d)
+		if err != nil {
+			log.WithError(err).Warn("Request failed, replying with the error")
+			response = queue.ErrorReply(toRPCError(err))
+		} else {
+			response.Body = body
+		}
+
+		if err := replier.Reply(ctx, d, response); err != nil {
+			log.WithError(err).Error("Can't send the reply, the requester will time out")
+		}
+		return nil
+	}
+}
+
+// toRPCError maps the service errors to the codes of queue.ErrorReply. Their messages are written
+// for clients, any other error is replied as an internal error.
+func toRPCError(err error) error {
+	var httpErr *errs.HTTPError
+	if !errors.As(err, &httpErr) {
+		return err
+	}
+
+	var code string
+	switch httpErr.StatusCode {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		code = queue.RPCCodeInvalidRequest
+	case http.StatusNotFound:
+		code = queue.RPCCodeNotFound
+	case http.StatusConflict:
+		code = queue.RPCCodeConflict
+	case http.StatusUnauthorized, http.StatusForbidden:
+		code = queue.RPCCodeForbidden
+	default:
+		return err
+	}
+	return queue.NewRPCError(code, httpErr.Message, err)
+}
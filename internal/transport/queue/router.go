@@ -2,16 +2,34 @@ package queueTransport
 
 import (
 	"context"
+	"fmt"
+	"go-api-template/internal/libs/logger"
 	"go-api-template/internal/libs/queue"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
 )
 
+// Router dispatches deliveries to the handler registered for their routing key. Handlers can be
+// registered for exact keys or topic patterns (`users.*`, `orders.#`). When several match, the most
+// specific one wins: an exact key first, then the pattern with the most literal words, then the one
+// with the fewest `#`, then the one with the fewest `*`, then the first registered.
 type Router struct {
-	handlers map[queue.RoutingKey]queue.RabbitMQHandler
+	routes      []route
+	fallback    queue.RabbitMQHandler
+	middlewares []Middleware
+}
+
+type route struct {
+	pattern queue.RoutingKey
+	handler queue.RabbitMQHandler
+	order   int
 }
 
 func NewRouter() *Router {
 	return &Router{
-		handlers: map[queue.RoutingKey]queue.RabbitMQHandler{},
+		fallback: DropUnmatched,
 	}
 }
 
@@ -19,14 +37,101 @@ func (r *Router) RegisterHandler(routingKey queue.RoutingKey, h queue.RabbitMQHa
 	if routingKey == "" || h == nil {
 		return
 	}
-	r.handlers[routingKey] = h
+
+	for i, existing := range r.routes {
+		if existing.pattern == routingKey {
+			r.routes[i].handler = h
+			return
+		}
+	}
+
+	r.routes = append(r.routes, route{pattern: routingKey, handler: h, order: len(r.routes)})
+	sort.SliceStable(r.routes, func(i, j int) bool {
+		return r.routes[i].moreSpecificThan(r.routes[j])
+	})
+}
+
+// Use adds middlewares run around every handler of the router, the fallback included.
+// They run before the middlewares the handler was registered with.
+func (r *Router) Use(middlewares ...Middleware) {
+	r.middlewares = append(r.middlewares, middlewares...)
+}
+
+// SetFallback sets the handler of the deliveries no registered pattern matches, DropUnmatched by default.
+func (r *Router) SetFallback(h queue.RabbitMQHandler) {
+	if h == nil {
+		h = DropUnmatched
+	}
+	r.fallback = h
+}
+
+// Bindings returns a binding for every registered key or pattern, so the queue receives what it handles.
+func (r *Router) Bindings() []queue.Binding {
+	bindings := make([]queue.Binding, 0, len(r.routes))
+	for _, route := range r.routes {
+		bindings = append(bindings, queue.Binding{RoutingKey: route.pattern})
+	}
+	return bindings
 }
 
 func (r *Router) Handle(ctx context.Context, d queue.Delivery) error {
-	h, ok := r.handlers[d.RoutingKey]
-	if !ok {
-		// Unknown routing key: ack/drop to avoid infinite requeue loops.
-		return nil
+	return ChainMiddlewares(r.match(d.RoutingKey), r.middlewares...)(ctx, d)
+}
+
+func (r *Router) match(routingKey queue.RoutingKey) queue.RabbitMQHandler {
+	for _, route := range r.routes {
+		if queue.MatchRoutingKey(route.pattern, routingKey) {
+			return route.handler
+		}
+	}
+	return r.fallback
+}
+
+// DropUnmatched acks the delivery without handling it, to avoid infinite requeue loops.
+func DropUnmatched(ctx context.Context, d queue.Delivery) error {
+	logger.FromContext(ctx).WithFields(logrus.Fields{
+		"routingKey": d.RoutingKey,
+		"messageId":  d.MessageID,
+	}).Warn("No handler for routing key, dropping message")
+	return nil
+}
+
+// DeadLetterUnmatched sends the delivery to the dead-letter queue, where it can be inspected and replayed.
+func DeadLetterUnmatched(ctx context.Context, d queue.Delivery) error {
+	return fmt.Errorf("%w: no handler for routing key %s", queue.ErrDeadLetter, d.RoutingKey)
+}
+
+func (r route) moreSpecificThan(other route) bool {
+	literal, single, multi := r.specificity()
+	otherLiteral, otherSingle, otherMulti := other.specificity()
+
+	exact := single == 0 && multi == 0
+	otherExact := otherSingle == 0 && otherMulti == 0
+	switch {
+	case exact != otherExact:
+		return exact
+	case literal != otherLiteral:
+		return literal > otherLiteral
+	case multi != otherMulti:
+		return multi < otherMulti
+	case single != otherSingle:
+		return single < otherSingle
+	default:
+		return r.order < other.order
+	}
+}
+
+// specificity counts the literal, `*` and `#` words of the pattern.
+func (r route) specificity() (literal int, single int, multi int) {
+	for _, word := range strings.Split(string(r.pattern), ".") {
+		switch word {
+		case "*":
+			single++
+		case "#":
+			multi++
+		default:
+			literal++
+		}
 	}
-	return h(ctx, d)
+	return literal, single, multi
 }
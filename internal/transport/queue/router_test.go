@@ -0,0 +1,88 @@
+package queueTransport
+
+import (
+	"context"
+	"errors"
+	"go-api-template/internal/libs/queue"
+	"testing"
+)
+
+// handlerNamed returns a handler that records its name in handled.
+func handlerNamed(name string, handled *string) queue.RabbitMQHandler {
+	return func(ctx context.Context, d queue.Delivery) error {
+		*handled = name
+		return nil
+	}
+}
+
+func TestRouterPrecedence(t *testing.T) {
+	tests := []struct {
+		name       string
+		patterns   []queue.RoutingKey
+		routingKey queue.RoutingKey
+		want       queue.RoutingKey
+	}{
+		{name: "exact key first", patterns: []queue.RoutingKey{"users.#", "users.created.#", "users.created"}, routingKey: "users.created", want: "users.created"},
+		{name: "most literal words", patterns: []queue.RoutingKey{"#", "users.#", "users.*.v2"}, routingKey: "users.created.v2", want: "users.*.v2"},
+		{name: "fewest #", patterns: []queue.RoutingKey{"users.#", "users.*"}, routingKey: "users.created", want: "users.*"},
+		{name: "fewest *", patterns: []queue.RoutingKey{"users.*.#", "users.#"}, routingKey: "users.created", want: "users.#"},
+		{name: "first registered", patterns: []queue.RoutingKey{"*.created", "users.*"}, routingKey: "users.created", want: "*.created"},
+		{name: "first registered, reversed", patterns: []queue.RoutingKey{"users.*", "*.created"}, routingKey: "users.created", want: "users.*"},
+		{name: "only matching patterns", patterns: []queue.RoutingKey{"orders.created", "users.*"}, routingKey: "users.deleted", want: "users.*"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var handled string
+			router := NewRouter()
+			for _, pattern := range tt.patterns {
+				router.RegisterHandler(pattern, handlerNamed(string(pattern), &handled))
+			}
+
+			if err := router.Handle(context.Background(), queue.Delivery{RoutingKey: tt.routingKey}); err != nil {
+				t.Fatalf("Handle: %v", err)
+			}
+			if handled != string(tt.want) {
+				t.Errorf("%s was handled by %q, want %q", tt.routingKey, handled, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouterRegisterReplacesTheHandler(t *testing.T) {
+	var handled string
+	router := NewRouter()
+	router.RegisterHandler("users.*", handlerNamed("first", &handled))
+	router.RegisterHandler("users.*", handlerNamed("second", &handled))
+
+	if err := router.Handle(context.Background(), queue.Delivery{RoutingKey: "users.created"}); err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	if handled != "second" {
+		t.Errorf("handled by %q, want second", handled)
+	}
+	if bindings := router.Bindings(); len(bindings) != 1 {
+		t.Errorf("bindings = %v, want one per pattern", bindings)
+	}
+}
+
+func TestRouterFallbacks(t *testing.T) {
+	router := NewRouter()
+	router.RegisterHandler("users.*", func(ctx context.Context, d queue.Delivery) error { return nil })
+	unmatched := queue.Delivery{RoutingKey: "orders.created"}
+
+	// Dropped (acked) by default.
+	if err := router.Handle(context.Background(), unmatched); err != nil {
+		t.Errorf("default fallback = %v, want nil", err)
+	}
+
+	router.SetFallback(DeadLetterUnmatched)
+	if err := router.Handle(context.Background(), unmatched); !errors.Is(err, queue.ErrDeadLetter) {
+		t.Errorf("DeadLetterUnmatched = %v, want ErrDeadLetter", err)
+	}
+
+	router.SetFallback(nil)
+	if err := router.Handle(context.Background(), unmatched); err != nil {
+		t.Errorf("nil fallback = %v, want DropUnmatched", err)
+	}
+}